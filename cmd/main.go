@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 	"path/filepath"
@@ -12,12 +13,36 @@ import (
 )
 
 func main() {
-	cfg, err := config.LoadConfig()
+	profileFlag := flag.String("profile", os.Getenv("TERMFTP_PROFILE"), "connection profile from config.yaml")
+	flag.Parse()
+
+	file, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
 
-	client, err := sftpclient.New(cfg)
+	profile, err := selectProfile(file, *profileFlag)
+	if err != nil {
+		log.Fatalf("select profile: %v", err)
+	}
+	cfg, err := file.Profile(profile)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+
+	// Without a state directory only two-way sync is unavailable.
+	syncStateDir, err := cfg.SyncStateDir()
+	if err != nil {
+		log.Printf("two-way sync disabled: %v", err)
+	}
+
+	var client *sftpclient.Client
+	prompter := ui.NewPrompter()
+	err = ui.Connect(cfg.Host, prompter, func() error {
+		var dialErr error
+		client, dialErr = sftpclient.New(cfg, prompter)
+		return dialErr
+	})
 	if err != nil {
 		log.Fatalf("init sftp client: %v", err)
 	}
@@ -26,18 +51,60 @@ func main() {
 	m := ui.New(ui.Options{
 		LocalRoot:  localRoot(),
 		RemoteRoot: cfg.Root,
-		Client:     client.Client,
+		Client:     client,
+		Prompter:   prompter,
 		Transfer: ui.TransferOptions{
-			BufferSize:       cfg.BufferSizeBytes(),
-			ParallelStreams:  cfg.ParallelStreams(),
-			ProgressInterval: cfg.ProgressInterval(),
+			BufferSize:          cfg.BufferSizeBytes(),
+			ParallelStreams:     cfg.ParallelStreams(),
+			ProgressInterval:    cfg.ProgressInterval(),
+			ConcurrentTransfers: cfg.ConcurrentTransfers(),
+			KeepPartial:         cfg.KeepPartialFiles(),
+			Resume:              cfg.ResumeTransfers(),
+			VerifyResume:        cfg.VerifyResume(),
+			VerifyChecksums:     cfg.VerifyChecksums(),
+			MaxBandwidth:        cfg.MaxBandwidth(),
+			TransferBandwidth:   cfg.MaxTransferBandwidth(),
+			MaxAttempts:         cfg.MaxAttempts(),
+			Conflict:            cfg.Transfer.Conflict,
+			PreserveTimes:       cfg.Preserves(config.PreserveTimes),
+			PreserveMode:        cfg.Preserves(config.PreserveMode),
+			Delta:               cfg.Transfer.Delta == config.DeltaOn,
+			SyncChecksums:       cfg.SyncChecksums(),
+			SyncDeletes:         cfg.SyncDeletes(),
+			SyncStateDir:        syncStateDir,
+			WatchDebounce:       cfg.WatchDebounce(),
+			WatchDeletes:        cfg.WatchDeletes(),
 		},
 	})
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+	program := tea.NewProgram(m, tea.WithAltScreen())
+	// Reconnects may need the user again, e.g. for a host key or OTP.
+	prompter.Attach(program)
+	defer prompter.Detach()
+	if _, err := program.Run(); err != nil {
 		log.Fatalf("run ui: %v", err)
 	}
 }
 
+// selectProfile picks the profile named on the command line, then the
+// configured default, and otherwise asks when there is more than one.
+func selectProfile(file *config.File, requested string) (string, error) {
+	if requested != "" {
+		return requested, nil
+	}
+	if file.DefaultProfile != "" {
+		return file.DefaultProfile, nil
+	}
+	names := file.ProfileNames()
+	if len(names) == 1 {
+		return names[0], nil
+	}
+	choices := make([]ui.Choice, 0, len(names))
+	for _, name := range names {
+		choices = append(choices, ui.Choice{Name: name, Detail: file.Describe(name)})
+	}
+	return ui.PickProfile(choices)
+}
+
 func localRoot() string {
 	if root := os.Getenv("TERMFTP_LOCAL_ROOT"); root != "" {
 		if abs, err := filepath.Abs(root); err == nil {
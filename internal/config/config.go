@@ -3,8 +3,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"os/exec"
+	"os/user"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,32 +17,94 @@ import (
 )
 
 const (
-	defaultMaxPacketKB        = 1024
-	defaultConcurrentRequests = 128
-	defaultParallelStreams    = 4
-	defaultBufferMiB          = 8
-	defaultProgressInterval   = 75 * time.Millisecond
+	defaultMaxPacketKB         = 1024
+	defaultConcurrentRequests  = 128
+	defaultParallelStreams     = 4
+	defaultBufferMiB           = 8
+	defaultProgressInterval    = 75 * time.Millisecond
+	defaultConcurrentTransfers = 2
+	defaultConnectTimeout      = 15 * time.Second
+	defaultKeepaliveInterval   = 15 * time.Second
+	defaultKeepaliveMaxMissed  = 3
 )
 
+// DefaultProfileName names the implicit profile built from top-level fields.
+const DefaultProfileName = "default"
+
+// File is the parsed config.yaml. Its top-level connection fields form the
+// implicit default profile; Profiles holds additional named connections.
+type File struct {
+	Config         `yaml:",inline"`
+	DefaultProfile string            `yaml:"defaultProfile"`
+	Profiles       map[string]Config `yaml:"profiles"`
+}
+
 type Config struct {
-	Host        string            `yaml:"host"`
-	Port        int               `yaml:"port"`
-	User        string            `yaml:"user"`
-	Password    string            `yaml:"password"`
+	Name        string `yaml:"-"`
+	Endpoint    `yaml:",inline"`
+	ProxyJump   JumpHosts         `yaml:"proxyJump"`
+	KnownHosts  []string          `yaml:"knownHosts"`
+	SSHConfig   string            `yaml:"sshConfig"`
 	Root        string            `yaml:"root"`
 	Performance PerformanceConfig `yaml:"performance"`
+	Transfer    TransferConfig    `yaml:"transfer"`
+	Sync        SyncConfig        `yaml:"sync"`
+	Watch       WatchConfig       `yaml:"watch"`
 	Cipher      string            `yaml:"cipher"`
+
+	// connectTimeout and keepaliveInterval take a duration such as "15s"
+	// or a plain number of seconds; connectTimeoutSec and
+	// keepaliveIntervalSec are the same settings in whole seconds. A
+	// keepalive interval of 0 turns keepalives off.
+	ConnectTimeoutSpec    string `yaml:"connectTimeout"`
+	KeepaliveIntervalSpec string `yaml:"keepaliveInterval"`
+	ConnectTimeoutSec     int    `yaml:"connectTimeoutSec"`
+	KeepaliveIntervalSec  *int   `yaml:"keepaliveIntervalSec"`
+	KeepaliveMaxMissed    int    `yaml:"keepaliveMaxMissed"`
+}
+
+// Endpoint is an SSH server address together with the credentials used to
+// log in to it. The target host and every jump host each have one.
+type Endpoint struct {
+	Host            string      `yaml:"host"`
+	Port            int         `yaml:"port"`
+	User            string      `yaml:"user"`
+	Password        string      `yaml:"password"`
+	PasswordEnv     string      `yaml:"passwordEnv"`
+	PasswordCommand string      `yaml:"passwordCommand"`
+	Keys            []KeyConfig `yaml:"keys"`
+	Agent           bool        `yaml:"agent"`
+	AgentSocket     string      `yaml:"agentSocket"`
+	// KeyboardInteractive enables challenge/response auth such as OTP
+	// prompts, answered interactively before the file panes open.
+	KeyboardInteractive bool `yaml:"keyboardInteractive"`
+}
+
+// KeyConfig points at a private key used for public-key authentication.
+// Passphrase is only needed for encrypted keys. Optional keys, such as those
+// picked up from ~/.ssh/config, are skipped when they cannot be loaded.
+type KeyConfig struct {
+	Path       string `yaml:"path"`
+	Passphrase string `yaml:"passphrase"`
+	Optional   bool   `yaml:"-"`
 }
 
+// PerformanceConfig tunes transfer throughput. MaxBandwidth caps all
+// transfers combined and MaxTransferBandwidth each one on its own; both take
+// bytes per second with an optional K/M/G suffix, e.g. "10M" or "512KiB", and
+// are unlimited when empty or "0".
 type PerformanceConfig struct {
-	MaxPacketKB        int `yaml:"maxPacketKB"`
-	ConcurrentRequests int `yaml:"concurrentRequests"`
-	ParallelStreams    int `yaml:"parallelStreams"`
-	BufferMiB          int `yaml:"bufferMiB"`
-	ProgressIntervalMs int `yaml:"progressIntervalMs"`
+	MaxPacketKB          int    `yaml:"maxPacketKB"`
+	ConcurrentRequests   int    `yaml:"concurrentRequests"`
+	ParallelStreams      int    `yaml:"parallelStreams"`
+	BufferMiB            int    `yaml:"bufferMiB"`
+	ProgressIntervalMs   int    `yaml:"progressIntervalMs"`
+	ConcurrentTransfers  int    `yaml:"concurrentTransfers"`
+	MaxBandwidth         string `yaml:"maxBandwidth"`
+	MaxTransferBandwidth string `yaml:"maxTransferBandwidth"`
 }
 
-func LoadConfig() (*Config, error) {
+func LoadConfig() (*File, error) {
 	path, err := resolveConfigPath()
 	if err != nil {
 		return nil, err
@@ -47,37 +114,265 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
 		return nil, fmt.Errorf("unmarshal config: %w", err)
 	}
+	// Timeouts are folded into seconds before profiles inherit them, so a
+	// profile's own setting wins whichever spelling either level uses.
+	if err := file.resolveTimeouts(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for name, profile := range file.Profiles {
+		if err := profile.resolveTimeouts(); err != nil {
+			return nil, fmt.Errorf("config profiles %s: %w", name, err)
+		}
+		file.Profiles[name] = profile
+	}
+	if _, ok := file.Profiles[DefaultProfileName]; ok {
+		return nil, fmt.Errorf("config profiles: %q is reserved for the top-level settings", DefaultProfileName)
+	}
+	if len(file.ProfileNames()) == 0 {
+		return nil, errors.New("config defines no host or profiles")
+	}
+	if file.DefaultProfile != "" && !file.HasProfile(file.DefaultProfile) {
+		return nil, fmt.Errorf("config defaultProfile %q does not exist", file.DefaultProfile)
+	}
+
+	return &file, nil
+}
+
+// ProfileNames lists the selectable profiles, the implicit default first and
+// the named ones sorted.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles)+1)
+	if f.hasImplicitDefault() {
+		names = append(names, DefaultProfileName)
+	}
+	named := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		named = append(named, name)
+	}
+	sort.Strings(named)
+	return append(names, named...)
+}
+
+func (f *File) HasProfile(name string) bool {
+	if _, ok := f.Profiles[name]; ok {
+		return true
+	}
+	return name == DefaultProfileName && f.hasImplicitDefault()
+}
+
+// Profile resolves, validates and returns the named connection settings.
+// Named profiles inherit unset performance tuning, transfer, sync and watch
+// behaviour, cipher and connection timeouts from the top level.
+func (f *File) Profile(name string) (*Config, error) {
+	var cfg Config
+	if named, ok := f.Profiles[name]; ok {
+		cfg = named
+		cfg.Performance.inherit(f.Performance)
+		cfg.Transfer.inherit(f.Transfer)
+		cfg.Sync.inherit(f.Sync)
+		cfg.Watch.inherit(f.Watch)
+		if cfg.Cipher == "" {
+			cfg.Cipher = f.Cipher
+		}
+		if cfg.ConnectTimeoutSec <= 0 {
+			cfg.ConnectTimeoutSec = f.ConnectTimeoutSec
+		}
+		if cfg.KeepaliveIntervalSec == nil {
+			cfg.KeepaliveIntervalSec = f.KeepaliveIntervalSec
+		}
+		if cfg.KeepaliveMaxMissed <= 0 {
+			cfg.KeepaliveMaxMissed = f.KeepaliveMaxMissed
+		}
+	} else if name == DefaultProfileName && f.hasImplicitDefault() {
+		cfg = f.Config
+	} else {
+		return nil, fmt.Errorf("profile %q not found in config", name)
+	}
+	cfg = cfg.clone()
+	cfg.Name = name
+	if err := cfg.applySSHConfig(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
 	cfg.applyDefaults()
 
 	if err := validate(&cfg); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	if err := cfg.resolvePassword(); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	for i := range cfg.ProxyJump {
+		if err := cfg.ProxyJump[i].resolvePassword(); err != nil {
+			return nil, fmt.Errorf("profile %s: proxyJump[%d]: %w", name, i, err)
+		}
 	}
 
 	return &cfg, nil
 }
 
+// Describe returns a short user@host:port summary of a profile for pickers.
+func (f *File) Describe(name string) string {
+	cfg, ok := f.Profiles[name]
+	if !ok {
+		cfg = f.Config
+	}
+	target := cfg.Host
+	if cfg.User != "" {
+		target = cfg.User + "@" + target
+	}
+	if cfg.Port != 0 {
+		target = fmt.Sprintf("%s:%d", target, cfg.Port)
+	}
+	return target
+}
+
+func (f *File) hasImplicitDefault() bool {
+	return f.Host != ""
+}
+
 func validate(cfg *Config) error {
-	if cfg.Host == "" {
+	if err := cfg.Endpoint.validate(); err != nil {
+		return err
+	}
+	for i := range cfg.ProxyJump {
+		if err := cfg.ProxyJump[i].validate(); err != nil {
+			return fmt.Errorf("proxyJump[%d]: %w", i, err)
+		}
+	}
+	if cfg.Root == "" {
+		return errors.New("config root is required")
+	}
+	if _, err := parseBandwidth(cfg.Performance.MaxBandwidth); err != nil {
+		return fmt.Errorf("performance.maxBandwidth: %w", err)
+	}
+	if _, err := parseBandwidth(cfg.Performance.MaxTransferBandwidth); err != nil {
+		return fmt.Errorf("performance.maxTransferBandwidth: %w", err)
+	}
+	if err := cfg.Transfer.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Sync.validate(); err != nil {
+		return err
+	}
+	return cfg.Watch.validate()
+}
+
+func (e *Endpoint) validate() error {
+	if e.Host == "" {
 		return errors.New("config host is required")
 	}
-	if cfg.User == "" {
+	if e.User == "" {
 		return errors.New("config user is required")
 	}
-	if cfg.Password == "" {
-		return errors.New("config password is required")
+	for i, key := range e.Keys {
+		if strings.TrimSpace(key.Path) == "" {
+			return fmt.Errorf("config keys[%d] path is required", i)
+		}
+	}
+	return nil
+}
+
+// resolvePassword fills Password from passwordEnv or passwordCommand when it
+// is not set inline. An empty result is fine: the password is then asked for
+// interactively if the server wants one.
+func (e *Endpoint) resolvePassword() error {
+	if e.Password != "" {
+		return nil
 	}
-	if cfg.Root == "" {
-		return errors.New("config root is required")
+	if e.PasswordEnv != "" {
+		value, ok := os.LookupEnv(e.PasswordEnv)
+		if !ok {
+			return fmt.Errorf("config passwordEnv: %s is not set", e.PasswordEnv)
+		}
+		e.Password = value
+		return nil
+	}
+	if strings.TrimSpace(e.PasswordCommand) != "" {
+		cmd := exec.Command("sh", "-c", e.PasswordCommand)
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("config passwordCommand: %w", err)
+		}
+		e.Password = strings.TrimRight(string(out), "\r\n")
 	}
 	return nil
 }
 
+func (e *Endpoint) applyDefaults() {
+	for i := range e.Keys {
+		e.Keys[i].Path = expandHome(strings.TrimSpace(e.Keys[i].Path))
+	}
+	if e.Agent && e.AgentSocket == "" {
+		e.AgentSocket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	e.AgentSocket = expandHome(e.AgentSocket)
+}
+
+// clone copies cfg deeply enough that resolving a profile never mutates the
+// parsed file.
+func (cfg Config) clone() Config {
+	cfg.Keys = append([]KeyConfig(nil), cfg.Keys...)
+	cfg.KnownHosts = append([]string(nil), cfg.KnownHosts...)
+	jumps := make(JumpHosts, len(cfg.ProxyJump))
+	for i, jump := range cfg.ProxyJump {
+		jump.Keys = append([]KeyConfig(nil), jump.Keys...)
+		jumps[i] = jump
+	}
+	cfg.ProxyJump = jumps
+	return cfg
+}
+
 func (cfg *Config) applyDefaults() {
+	cfg.Endpoint.applyDefaults()
+	// Like OpenSSH, log in as the local user unless told otherwise.
+	localUser := localUsername()
+	if cfg.User == "" {
+		cfg.User = localUser
+	}
+	for i := range cfg.ProxyJump {
+		jump := &cfg.ProxyJump[i].Endpoint
+		if jump.User == "" {
+			jump.User = localUser
+		}
+		// Like OpenSSH, offer the target's identities to bastions that do
+		// not list keys of their own.
+		if len(jump.Keys) == 0 {
+			jump.Keys = append([]KeyConfig(nil), cfg.Keys...)
+		}
+		if !jump.Agent && cfg.Agent {
+			jump.Agent = true
+			jump.AgentSocket = cfg.AgentSocket
+		}
+		jump.applyDefaults()
+	}
+	if len(cfg.KnownHosts) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			cfg.KnownHosts = []string{filepath.Join(home, ".ssh", "known_hosts")}
+		}
+	}
+	for i := range cfg.KnownHosts {
+		cfg.KnownHosts[i] = expandHome(strings.TrimSpace(cfg.KnownHosts[i]))
+	}
+	if cfg.ConnectTimeoutSec <= 0 {
+		cfg.ConnectTimeoutSec = int(defaultConnectTimeout / time.Second)
+	}
+	if cfg.KeepaliveIntervalSec == nil {
+		seconds := int(defaultKeepaliveInterval / time.Second)
+		cfg.KeepaliveIntervalSec = &seconds
+	}
+	if cfg.KeepaliveMaxMissed <= 0 {
+		cfg.KeepaliveMaxMissed = defaultKeepaliveMaxMissed
+	}
 	cfg.Performance.applyDefaults()
+	cfg.Transfer.applyDefaults()
+	cfg.Sync.applyDefaults()
+	cfg.Watch.applyDefaults()
 }
 
 func (p *PerformanceConfig) applyDefaults() {
@@ -96,6 +391,36 @@ func (p *PerformanceConfig) applyDefaults() {
 	if p.ProgressIntervalMs <= 0 {
 		p.ProgressIntervalMs = int(defaultProgressInterval / time.Millisecond)
 	}
+	if p.ConcurrentTransfers <= 0 {
+		p.ConcurrentTransfers = defaultConcurrentTransfers
+	}
+}
+
+func (p *PerformanceConfig) inherit(parent PerformanceConfig) {
+	if p.MaxPacketKB <= 0 {
+		p.MaxPacketKB = parent.MaxPacketKB
+	}
+	if p.ConcurrentRequests <= 0 {
+		p.ConcurrentRequests = parent.ConcurrentRequests
+	}
+	if p.ParallelStreams <= 0 {
+		p.ParallelStreams = parent.ParallelStreams
+	}
+	if p.BufferMiB <= 0 {
+		p.BufferMiB = parent.BufferMiB
+	}
+	if p.ProgressIntervalMs <= 0 {
+		p.ProgressIntervalMs = parent.ProgressIntervalMs
+	}
+	if p.ConcurrentTransfers <= 0 {
+		p.ConcurrentTransfers = parent.ConcurrentTransfers
+	}
+	if p.MaxBandwidth == "" {
+		p.MaxBandwidth = parent.MaxBandwidth
+	}
+	if p.MaxTransferBandwidth == "" {
+		p.MaxTransferBandwidth = parent.MaxTransferBandwidth
+	}
 }
 
 func (cfg *Config) MaxPacketBytes() int {
@@ -118,6 +443,122 @@ func (cfg *Config) ProgressInterval() time.Duration {
 	return time.Duration(clampInt(cfg.Performance.ProgressIntervalMs, 25, 1000)) * time.Millisecond
 }
 
+// ConnectTimeout bounds dialling and the SSH handshake of each hop.
+func (cfg *Config) ConnectTimeout() time.Duration {
+	return time.Duration(clampInt(cfg.ConnectTimeoutSec, 1, 300)) * time.Second
+}
+
+// KeepaliveInterval is how often an idle connection is probed, or 0 when
+// keepalives are off.
+func (cfg *Config) KeepaliveInterval() time.Duration {
+	if cfg.KeepaliveIntervalSec == nil || *cfg.KeepaliveIntervalSec <= 0 {
+		return 0
+	}
+	return time.Duration(clampInt(*cfg.KeepaliveIntervalSec, 1, 600)) * time.Second
+}
+
+// resolveTimeouts converts connectTimeout and keepaliveInterval to the
+// seconds fields.
+func (cfg *Config) resolveTimeouts() error {
+	if cfg.ConnectTimeoutSpec != "" {
+		if cfg.ConnectTimeoutSec != 0 {
+			return errors.New("set connectTimeout or connectTimeoutSec, not both")
+		}
+		seconds, err := parseSeconds(cfg.ConnectTimeoutSpec)
+		if err != nil {
+			return fmt.Errorf("connectTimeout: %w", err)
+		}
+		cfg.ConnectTimeoutSec = seconds
+	}
+	if cfg.KeepaliveIntervalSpec != "" {
+		if cfg.KeepaliveIntervalSec != nil {
+			return errors.New("set keepaliveInterval or keepaliveIntervalSec, not both")
+		}
+		seconds, err := parseSeconds(cfg.KeepaliveIntervalSpec)
+		if err != nil {
+			return fmt.Errorf("keepaliveInterval: %w", err)
+		}
+		cfg.KeepaliveIntervalSec = &seconds
+	}
+	cfg.ConnectTimeoutSpec, cfg.KeepaliveIntervalSpec = "", ""
+	return nil
+}
+
+// parseSeconds reads a duration such as "90s" or "2m", or a bare number of
+// seconds, rounded up to whole seconds.
+func parseSeconds(value string) (int, error) {
+	s := strings.TrimSpace(value)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("negative duration %q", value)
+		}
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// ConcurrentTransfers is how many queued transfers may run at once.
+func (cfg *Config) ConcurrentTransfers() int {
+	return clampInt(cfg.Performance.ConcurrentTransfers, 1, 8)
+}
+
+// MaxBandwidth is the cap on all transfers combined in bytes per second, or
+// 0 when unlimited.
+func (cfg *Config) MaxBandwidth() int64 {
+	rate, _ := parseBandwidth(cfg.Performance.MaxBandwidth)
+	return rate
+}
+
+// MaxTransferBandwidth is the starting cap for each individual transfer in
+// bytes per second, or 0 when unlimited.
+func (cfg *Config) MaxTransferBandwidth() int64 {
+	rate, _ := parseBandwidth(cfg.Performance.MaxTransferBandwidth)
+	return rate
+}
+
+// parseBandwidth reads a rate such as "800K", "10MiB", "1.5MB/s" or "0".
+// Suffixes are binary multiples. A positive rate never rounds down to 0,
+// which would mean unlimited.
+func parseBandwidth(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(strings.ToUpper(s), "/S")
+	s = strings.TrimSuffix(s, "B")
+	multiplier := 1.0
+	if n := len(s); n > 0 {
+		unit := s[n-1]
+		if unit == 'I' && n > 1 {
+			unit = s[n-2]
+		}
+		switch unit {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = strings.TrimSuffix(strings.TrimSuffix(s, "I"), string(unit))
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	rate := math.Ceil(n * multiplier)
+	if err != nil || n < 0 || math.IsNaN(rate) || rate >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid bandwidth %q", value)
+	}
+	return int64(rate), nil
+}
+
 func clampInt(value, min, max int) int {
 	if value < min {
 		return min
@@ -135,6 +576,18 @@ func (cfg *Config) SSHCiphers() []string {
 	return []string{strings.TrimSpace(cfg.Cipher)}
 }
 
+// KnownHostsPath returns the termftp-specific known_hosts file that accepted
+// host keys are written to.
+func KnownHostsPath() string {
+	if cfgHome := os.Getenv("XDG_CONFIG_HOME"); cfgHome != "" {
+		return filepath.Join(cfgHome, "termftp", "known_hosts")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "termftp", "known_hosts")
+	}
+	return filepath.Join(".", "known_hosts")
+}
+
 func resolveConfigPath() (string, error) {
 	for _, candidate := range configCandidates() {
 		if candidate == "" {
@@ -174,6 +627,26 @@ func configCandidates() []string {
 	return dedupStrings(paths)
 }
 
+// localUsername is the name of the user running termftp, or "" when it
+// cannot be determined.
+func localUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
 func dedupStrings(values []string) []string {
 	seen := make(map[string]struct{}, len(values))
 	result := make([]string, 0, len(values))
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadTestConfig loads data as the config file, with a home directory of
+// its own so that no real ssh_config is picked up.
+func loadTestConfig(t *testing.T, data string) (*File, error) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TERMFTP_CONFIG", path)
+	t.Setenv("HOME", dir)
+	return LoadConfig()
+}
+
+func TestTimeouts(t *testing.T) {
+	base := "host: example.com\nuser: alice\nroot: /\n"
+	tests := []struct {
+		name      string
+		yaml      string
+		profile   string
+		connect   time.Duration
+		keepalive time.Duration
+		wantErr   string
+	}{
+		{name: "defaults", connect: 15 * time.Second, keepalive: 15 * time.Second},
+		{name: "durations", yaml: "connectTimeout: 1m\nkeepaliveInterval: 30s\n", connect: time.Minute, keepalive: 30 * time.Second},
+		{name: "bare seconds", yaml: "connectTimeout: 20\nkeepaliveInterval: 5\n", connect: 20 * time.Second, keepalive: 5 * time.Second},
+		{name: "seconds keys", yaml: "connectTimeoutSec: 20\nkeepaliveIntervalSec: 5\n", connect: 20 * time.Second, keepalive: 5 * time.Second},
+		{name: "sub-second rounds up", yaml: "keepaliveInterval: 1500ms\n", connect: 15 * time.Second, keepalive: 2 * time.Second},
+		{name: "keepalive off", yaml: "keepaliveInterval: 0\n", connect: 15 * time.Second, keepalive: 0},
+		{name: "keepalive off in seconds", yaml: "keepaliveIntervalSec: 0\n", connect: 15 * time.Second, keepalive: 0},
+		{name: "clamped", yaml: "connectTimeout: 1h\nkeepaliveInterval: 1h\n", connect: 300 * time.Second, keepalive: 600 * time.Second},
+		{
+			name:      "profile overrides other spelling",
+			yaml:      "connectTimeout: 1m\nkeepaliveInterval: 0\nprofiles:\n  work:\n    host: work\n    user: bob\n    root: /\n    connectTimeoutSec: 10\n",
+			profile:   "work",
+			connect:   10 * time.Second,
+			keepalive: 0,
+		},
+		{name: "both spellings", yaml: "connectTimeout: 1m\nconnectTimeoutSec: 10\n", wantErr: "not both"},
+		{name: "invalid", yaml: "keepaliveInterval: soon\n", wantErr: "keepaliveInterval"},
+		{name: "negative", yaml: "connectTimeout: -5s\n", wantErr: "negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := loadTestConfig(t, base+tt.yaml)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			profile := tt.profile
+			if profile == "" {
+				profile = DefaultProfileName
+			}
+			cfg, err := file.Profile(profile)
+			if err != nil {
+				t.Fatalf("Profile: %v", err)
+			}
+			if got := cfg.ConnectTimeout(); got != tt.connect {
+				t.Errorf("ConnectTimeout = %s, want %s", got, tt.connect)
+			}
+			if got := cfg.KeepaliveInterval(); got != tt.keepalive {
+				t.Errorf("KeepaliveInterval = %s, want %s", got, tt.keepalive)
+			}
+		})
+	}
+}
+
+func TestSyncDefaults(t *testing.T) {
+	file, err := loadTestConfig(t, "host: example.com\nuser: alice\nroot: /\n")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	cfg, err := file.Profile(DefaultProfileName)
+	if err != nil {
+		t.Fatalf("Profile: %v", err)
+	}
+	if cfg.SyncDeletes() {
+		t.Error("sync deletes by default")
+	}
+	if cfg.SyncChecksums() {
+		t.Error("sync compares checksums by default")
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "0", want: 0},
+		{in: "  512 ", want: 512},
+		{in: "800K", want: 800 << 10},
+		{in: "800k", want: 800 << 10},
+		{in: "10M", want: 10 << 20},
+		{in: "10MB", want: 10 << 20},
+		{in: "10MiB", want: 10 << 20},
+		{in: "10 MiB/s", want: 10 << 20},
+		{in: "1.5MB/s", want: 3 << 19},
+		{in: "2G", want: 2 << 30},
+		{in: "100B", want: 100},
+		{in: "0.5", want: 1},
+		{in: "-1", wantErr: true},
+		{in: "-1M", wantErr: true},
+		{in: "fast", wantErr: true},
+		{in: "M", wantErr: true},
+		{in: "10T", wantErr: true},
+		{in: "10I", wantErr: true},
+		{in: "10KK", wantErr: true},
+		{in: "inf", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "1e30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseBandwidth(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseBandwidth(%q) = %d, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBandwidth(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseBandwidth(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
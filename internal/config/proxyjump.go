@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JumpHost is a bastion the connection is tunnelled through. In YAML it is
+// either a mapping with its own host and auth settings or an OpenSSH-style
+// "[user@]host[:port]" string.
+type JumpHost struct {
+	Endpoint `yaml:",inline"`
+}
+
+// JumpHosts lists bastions in the order they are dialled. Like OpenSSH's
+// ProxyJump it also accepts a single comma-separated string.
+type JumpHosts []JumpHost
+
+func (j *JumpHost) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		endpoint, err := parseJumpSpec(node.Value)
+		if err != nil {
+			return err
+		}
+		j.Endpoint = endpoint
+		return nil
+	}
+	type plain JumpHost
+	return node.Decode((*plain)(j))
+}
+
+func (j *JumpHosts) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		hosts, err := parseJumpList(node.Value)
+		if err != nil {
+			return err
+		}
+		*j = hosts
+		return nil
+	}
+	var hosts []JumpHost
+	if err := node.Decode(&hosts); err != nil {
+		return err
+	}
+	*j = hosts
+	return nil
+}
+
+// parseJumpList parses an OpenSSH ProxyJump value; "none" yields no hosts.
+func parseJumpList(value string) (JumpHosts, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "none") {
+		return nil, nil
+	}
+	var hosts JumpHosts
+	for _, spec := range strings.Split(value, ",") {
+		endpoint, err := parseJumpSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, JumpHost{Endpoint: endpoint})
+	}
+	return hosts, nil
+}
+
+// parseJumpSpec parses "[ssh://][user@]host[:port]".
+func parseJumpSpec(spec string) (Endpoint, error) {
+	spec = strings.TrimPrefix(strings.TrimSpace(spec), "ssh://")
+	if spec == "" {
+		return Endpoint{}, fmt.Errorf("empty proxy jump host")
+	}
+	var endpoint Endpoint
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		endpoint.User = spec[:at]
+		spec = spec[at+1:]
+	}
+	host, portStr, err := net.SplitHostPort(spec)
+	if err != nil {
+		endpoint.Host = strings.Trim(spec, "[]")
+		return endpoint, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("proxy jump %q: invalid port", spec)
+	}
+	endpoint.Host = host
+	endpoint.Port = port
+	return endpoint, nil
+}
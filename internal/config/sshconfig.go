@@ -0,0 +1,294 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sshConfigDisabled turns off ~/.ssh/config lookups when used as sshConfig.
+const sshConfigDisabled = "none"
+
+const maxSSHConfigDepth = 8
+
+// sshHost holds the OpenSSH client settings termftp understands for a single
+// host alias, resolved with OpenSSH's first-match-wins rules.
+type sshHost struct {
+	HostName      string
+	Port          int
+	User          string
+	IdentityFiles []string
+	ProxyJump     string
+}
+
+// applySSHConfig fills connection fields from the OpenSSH client config for
+// the configured host alias and for each jump host. Fields set explicitly in
+// termftp config win.
+func (cfg *Config) applySSHConfig() error {
+	path := strings.TrimSpace(cfg.SSHConfig)
+	if path == sshConfigDisabled {
+		return nil
+	}
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+	path = expandHome(path)
+	if _, err := os.Stat(path); err != nil {
+		if explicit {
+			return fmt.Errorf("read ssh config: %w", err)
+		}
+		return nil
+	}
+
+	host, err := cfg.Endpoint.applySSHHost(path)
+	if err != nil {
+		return err
+	}
+	if len(cfg.ProxyJump) == 0 && host.ProxyJump != "" {
+		if cfg.ProxyJump, err = parseJumpList(host.ProxyJump); err != nil {
+			return fmt.Errorf("ssh config ProxyJump: %w", err)
+		}
+	}
+	for i := range cfg.ProxyJump {
+		if _, err := cfg.ProxyJump[i].applySSHHost(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Endpoint) applySSHHost(path string) (sshHost, error) {
+	host, err := lookupSSHConfig(path, e.Host)
+	if err != nil {
+		return sshHost{}, err
+	}
+	if e.Port == 0 {
+		e.Port = host.Port
+	}
+	if e.User == "" {
+		e.User = host.User
+	}
+	if e.User == "" {
+		e.User = localUsername()
+	}
+	if host.HostName != "" {
+		e.Host = expandSSHTokens(host.HostName, e.Host, e)
+	}
+	if len(e.Keys) == 0 {
+		for _, file := range host.IdentityFiles {
+			e.Keys = append(e.Keys, KeyConfig{Path: expandSSHTokens(file, e.Host, e), Optional: true})
+		}
+	}
+	return host, nil
+}
+
+func lookupSSHConfig(path, alias string) (sshHost, error) {
+	var host sshHost
+	if err := parseSSHConfig(path, alias, &host, 0); err != nil {
+		return sshHost{}, fmt.Errorf("read ssh config: %w", err)
+	}
+	return host, nil
+}
+
+func parseSSHConfig(path, alias string, host *sshHost, depth int) error {
+	if depth > maxSSHConfigDepth {
+		return fmt.Errorf("%s: include nested too deeply", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseSSHConfigReader(f, filepath.Dir(path), alias, host, depth)
+}
+
+func parseSSHConfigReader(r io.Reader, dir, alias string, host *sshHost, depth int) error {
+	// Settings before the first Host line apply to every host.
+	matching := true
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+		switch keyword {
+		case "host":
+			matching = matchSSHHost(alias, args)
+			continue
+		case "match":
+			// Match criteria are not evaluated; skip the block.
+			matching = len(args) == 1 && strings.EqualFold(args[0], "all")
+			continue
+		}
+		if !matching || len(args) == 0 {
+			continue
+		}
+		switch keyword {
+		case "include":
+			for _, pattern := range args {
+				if err := includeSSHConfig(pattern, dir, alias, host, depth); err != nil {
+					return err
+				}
+			}
+		case "hostname":
+			if host.HostName == "" {
+				host.HostName = args[0]
+			}
+		case "port":
+			if host.Port == 0 {
+				if port, err := strconv.Atoi(args[0]); err == nil {
+					host.Port = port
+				}
+			}
+		case "user":
+			if host.User == "" {
+				host.User = args[0]
+			}
+		case "proxyjump":
+			if host.ProxyJump == "" {
+				host.ProxyJump = args[0]
+			}
+		case "identityfile":
+			if !strings.EqualFold(args[0], "none") {
+				host.IdentityFiles = append(host.IdentityFiles, args[0])
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func includeSSHConfig(pattern, dir, alias string, host *sshHost, depth int) error {
+	pattern = expandHome(pattern)
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := parseSSHConfig(match, alias, host, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitSSHConfigLine returns the lower-cased keyword and its arguments,
+// honouring "keyword=value" syntax and double-quoted arguments.
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil
+	}
+	keyword := strings.ToLower(line[:end])
+	rest := strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		case r == '#' && !quoted && current.Len() == 0:
+			return keyword, args
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return keyword, args
+}
+
+// matchSSHHost reports whether alias matches a Host line. A matching negated
+// pattern excludes the host even if another pattern matches.
+func matchSSHHost(alias string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if !matchSSHPattern(pattern, alias) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// matchSSHPattern matches s against an ssh_config pattern, where '*' stands
+// for any run of characters and '?' for exactly one. Every other character,
+// including '[' and '\', is literal.
+func matchSSHPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := range len(s) + 1 {
+				if matchSSHPattern(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return s == ""
+}
+
+// expandSSHTokens expands the OpenSSH tokens termftp can resolve: %h, %p,
+// %r, %u, %d and %%, plus a leading ~.
+func expandSSHTokens(value, hostname string, cfg *Endpoint) string {
+	if !strings.Contains(value, "%") {
+		return expandHome(value)
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	home, _ := os.UserHomeDir()
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%h", hostname,
+		"%p", strconv.Itoa(port),
+		"%r", cfg.User,
+		"%u", localUsername(),
+		"%d", home,
+	)
+	return expandHome(replacer.Replace(value))
+}
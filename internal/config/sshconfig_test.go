@@ -0,0 +1,224 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchSSHHost(t *testing.T) {
+	tests := []struct {
+		alias    string
+		patterns string
+		want     bool
+	}{
+		{alias: "web", patterns: "web", want: true},
+		{alias: "web", patterns: "Web"},
+		{alias: "web", patterns: "db web", want: true},
+		{alias: "anything", patterns: "*", want: true},
+		{alias: "a.example.com", patterns: "*.example.com", want: true},
+		{alias: "example.com", patterns: "*.example.com"},
+		{alias: "web1", patterns: "web?", want: true},
+		{alias: "web12", patterns: "web?"},
+		{alias: "a.b.c", patterns: "a*c", want: true},
+		{alias: "web[1]", patterns: "web[1]", want: true},
+		{alias: "web1", patterns: "web[1]"},
+		{alias: "bastion", patterns: "* !bastion"},
+		{alias: "bastion", patterns: "!bastion *"},
+		{alias: "web", patterns: "* !bastion", want: true},
+		{alias: "web", patterns: "!bastion"},
+		{alias: "db.internal", patterns: "*.internal !db.*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias+" "+tt.patterns, func(t *testing.T) {
+			if got := matchSSHHost(tt.alias, strings.Fields(tt.patterns)); got != tt.want {
+				t.Fatalf("matchSSHHost(%q, %q) = %v, want %v", tt.alias, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSSHConfigLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		keyword string
+		args    []string
+	}{
+		{line: "  # comment"},
+		{line: ""},
+		{line: "HostName example.com", keyword: "hostname", args: []string{"example.com"}},
+		{line: "\tPort=2222", keyword: "port", args: []string{"2222"}},
+		{line: "User = alice", keyword: "user", args: []string{"alice"}},
+		{line: `IdentityFile "~/my keys/id"`, keyword: "identityfile", args: []string{"~/my keys/id"}},
+		{line: "Host a b # trailing", keyword: "host", args: []string{"a", "b"}},
+		{line: "Host a#b", keyword: "host", args: []string{"a#b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			keyword, args := splitSSHConfigLine(tt.line)
+			if keyword != tt.keyword || !reflect.DeepEqual(args, tt.args) {
+				t.Fatalf("splitSSHConfigLine(%q) = %q %q, want %q %q", tt.line, keyword, args, tt.keyword, tt.args)
+			}
+		})
+	}
+}
+
+func writeSSHConfig(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeSSHConfig(t, dir, "conf.d/10-work", `
+Host *.work
+    User worker
+    IdentityFile ~/.ssh/work
+`)
+	writeSSHConfig(t, dir, "db.conf", "HostName db.example.com\n")
+	path := writeSSHConfig(t, dir, "config", `
+# Settings before any Host apply everywhere, but only where unset.
+IdentityFile ~/.ssh/global
+Include conf.d/*
+
+Host web
+    HostName web.example.com
+    Port 2222
+
+Host web db
+    # Only read for hosts this block matches.
+    Include db.conf
+    HostName ignored.example.com
+    User alice
+    ProxyJump bastion
+
+Match host web
+    User matched
+
+Match all
+    IdentityFile ~/.ssh/all
+
+Host !gw.work *.work
+    Port 2200
+    IdentityFile none
+
+Host *
+    User fallback
+    Port 22
+`)
+	tests := []struct {
+		alias string
+		want  sshHost
+	}{
+		{
+			alias: "web",
+			want: sshHost{
+				HostName:      "web.example.com",
+				Port:          2222,
+				User:          "alice",
+				ProxyJump:     "bastion",
+				IdentityFiles: []string{"~/.ssh/global", "~/.ssh/all"},
+			},
+		},
+		{
+			alias: "db",
+			want: sshHost{
+				HostName:      "db.example.com",
+				Port:          22,
+				User:          "alice",
+				ProxyJump:     "bastion",
+				IdentityFiles: []string{"~/.ssh/global", "~/.ssh/all"},
+			},
+		},
+		{
+			alias: "ci.work",
+			want: sshHost{
+				Port:          2200,
+				User:          "worker",
+				IdentityFiles: []string{"~/.ssh/global", "~/.ssh/work", "~/.ssh/all"},
+			},
+		},
+		{
+			alias: "gw.work",
+			want: sshHost{
+				Port:          22,
+				User:          "worker",
+				IdentityFiles: []string{"~/.ssh/global", "~/.ssh/work", "~/.ssh/all"},
+			},
+		},
+		{
+			alias: "other",
+			want: sshHost{
+				Port:          22,
+				User:          "fallback",
+				IdentityFiles: []string{"~/.ssh/global", "~/.ssh/all"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			got, err := lookupSSHConfig(path, tt.alias)
+			if err != nil {
+				t.Fatalf("lookupSSHConfig: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("lookupSSHConfig(%q) = %+v, want %+v", tt.alias, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupSSHConfigIncludeLoop(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSSHConfig(t, dir, "config", "Include config\n")
+	if _, err := lookupSSHConfig(path, "web"); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Fatalf("error = %v, want an include depth error", err)
+	}
+}
+
+func TestApplySSHHost(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	path := writeSSHConfig(t, dir, "config", `
+Host web
+    HostName %h.example.com
+    User alice
+    Port 2222
+    IdentityFile ~/.ssh/%r@%h-%p
+    IdentityFile %d/literal%%
+`)
+
+	e := Endpoint{Host: "web"}
+	if _, err := e.applySSHHost(path); err != nil {
+		t.Fatalf("applySSHHost: %v", err)
+	}
+	want := Endpoint{
+		Host: "web.example.com",
+		Port: 2222,
+		User: "alice",
+		Keys: []KeyConfig{
+			{Path: filepath.Join(dir, ".ssh", "alice@web.example.com-2222"), Optional: true},
+			{Path: filepath.Join(dir, "literal%"), Optional: true},
+		},
+	}
+	if !reflect.DeepEqual(e, want) {
+		t.Fatalf("endpoint = %+v, want %+v", e, want)
+	}
+
+	explicit := Endpoint{Host: "web", Port: 22, User: "bob", Keys: []KeyConfig{{Path: "own"}}}
+	if _, err := explicit.applySSHHost(path); err != nil {
+		t.Fatalf("applySSHHost: %v", err)
+	}
+	if explicit.Port != 22 || explicit.User != "bob" || len(explicit.Keys) != 1 || explicit.Keys[0].Path != "own" {
+		t.Fatalf("explicit settings overridden: %+v", explicit)
+	}
+}
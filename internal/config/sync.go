@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	SyncCompareTime     = "mtime"
+	SyncCompareChecksum = "checksum"
+
+	SyncDeleteOn  = "on"
+	SyncDeleteOff = "off"
+)
+
+// SyncConfig controls directory sync. Compare decides when a destination file
+// is already up to date: "mtime" (the default) when its size and
+// modification time match the source, "checksum" when its size and SHA-256
+// do. Delete set to "on" removes destination entries that no longer exist at
+// the source, so the destination mirrors it; "off" (the default) keeps them.
+type SyncConfig struct {
+	Compare string `yaml:"compare"`
+	Delete  string `yaml:"delete"`
+}
+
+func (s *SyncConfig) applyDefaults() {
+	if s.Compare == "" {
+		s.Compare = SyncCompareTime
+	}
+	if s.Delete == "" {
+		s.Delete = SyncDeleteOff
+	}
+}
+
+func (s *SyncConfig) inherit(parent SyncConfig) {
+	if s.Compare == "" {
+		s.Compare = parent.Compare
+	}
+	if s.Delete == "" {
+		s.Delete = parent.Delete
+	}
+}
+
+func (s *SyncConfig) validate() error {
+	switch s.Compare {
+	case SyncCompareTime, SyncCompareChecksum:
+	default:
+		return fmt.Errorf("sync.compare must be %q or %q, got %q", SyncCompareTime, SyncCompareChecksum, s.Compare)
+	}
+	switch s.Delete {
+	case SyncDeleteOn, SyncDeleteOff:
+	default:
+		return fmt.Errorf("sync.delete must be %q or %q, got %q", SyncDeleteOn, SyncDeleteOff, s.Delete)
+	}
+	return nil
+}
+
+// SyncChecksums reports whether sync compares file contents rather than
+// modification times.
+func (cfg *Config) SyncChecksums() bool {
+	return cfg.Sync.Compare == SyncCompareChecksum
+}
+
+// SyncDeletes reports whether sync removes destination entries missing from
+// the source.
+func (cfg *Config) SyncDeletes() bool {
+	return cfg.Sync.Delete == SyncDeleteOn
+}
+
+// SyncStateDir is where two-way sync keeps what it last synced with this
+// profile's server, one file per pair of directories. It fails rather than
+// guess when there is no state directory or user to key the server by.
+func (cfg *Config) SyncStateDir() (string, error) {
+	var base string
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		base = filepath.Join(stateHome, "termftp", "sync")
+	} else if home, err := os.UserHomeDir(); err == nil {
+		base = filepath.Join(home, ".local", "state", "termftp", "sync")
+	} else {
+		return "", fmt.Errorf("sync state directory: %w", err)
+	}
+	user := cfg.User
+	if user == "" {
+		user = localUsername()
+	}
+	if user == "" {
+		return "", fmt.Errorf("sync state directory: no user for %s", cfg.Host)
+	}
+	server := user + "@" + cfg.Host
+	if cfg.Port != 0 {
+		server += "_" + strconv.Itoa(cfg.Port)
+	}
+	return filepath.Join(base, strings.ReplaceAll(server, string(filepath.Separator), "_")), nil
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	PartialFilesDelete = "delete"
+	PartialFilesKeep   = "keep"
+
+	ResumeTail = "tail"
+	ResumeSize = "size"
+	ResumeOff  = "off"
+
+	VerifyOff    = "off"
+	VerifySHA256 = "sha256"
+
+	ConflictAsk       = "ask"
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"
+	ConflictNewer     = "newer"
+	ConflictSize      = "size"
+
+	PreserveTimes = "times"
+	PreserveMode  = "mode"
+
+	DeltaOff = "off"
+	DeltaOn  = "on"
+
+	defaultMaxAttempts = 5
+)
+
+// TransferConfig controls how file transfers behave, as opposed to how fast
+// they run (see PerformanceConfig). PartialFiles decides what happens to a
+// half-written destination when a transfer is canceled: "delete" (the
+// default) or "keep". Resume picks how an existing partial destination is
+// continued from the point recorded in its hidden ".termftp-resume" sidecar:
+// "tail" (the default) first checks that the bytes just before that point
+// match the source, "size" trusts the recorded point alone and "off" always
+// starts over. Verify set to "sha256" compares checksums of source and
+// destination after every file; it is "off" by default. MaxAttempts bounds
+// how often a chunk is tried before a transient error fails the transfer; 1
+// disables retries. Conflict chooses what happens when a destination file
+// already exists: "ask" (the default) shows a dialog, "overwrite", "skip",
+// "rename" keeps both by giving the new copy a numbered name, "newer"
+// overwrites only older files and "size" only files of a different size.
+// Preserve lists the metadata copied to the destination: "times" for access
+// and modification times, "mode" for permission bits. It is empty by default;
+// a named profile can clear an inherited list with []. Delta set to "on"
+// sends only the changed blocks of files that already exist at the
+// destination; it is "off" by default. Blocks are compared at fixed 4 MiB
+// offsets rather than with a rolling checksum, so bytes inserted or removed
+// early in a file shift every later block and the rest of it is sent again.
+// Downloads are patched in place. Uploads are built in the temporary file
+// from the server's copy of the unchanged blocks, which needs the copy-data
+// extension of OpenSSH 9.0 or later; other servers receive the whole file.
+type TransferConfig struct {
+	PartialFiles string   `yaml:"partialFiles"`
+	Resume       string   `yaml:"resume"`
+	Verify       string   `yaml:"verify"`
+	MaxAttempts  int      `yaml:"maxAttempts"`
+	Conflict     string   `yaml:"conflict"`
+	Preserve     []string `yaml:"preserve"`
+	Delta        string   `yaml:"delta"`
+}
+
+func (t *TransferConfig) applyDefaults() {
+	if t.PartialFiles == "" {
+		t.PartialFiles = PartialFilesDelete
+	}
+	if t.Resume == "" {
+		t.Resume = ResumeTail
+	}
+	if t.Verify == "" {
+		t.Verify = VerifyOff
+	}
+	if t.MaxAttempts <= 0 {
+		t.MaxAttempts = defaultMaxAttempts
+	}
+	if t.Conflict == "" {
+		t.Conflict = ConflictAsk
+	}
+	if t.Delta == "" {
+		t.Delta = DeltaOff
+	}
+}
+
+func (t *TransferConfig) inherit(parent TransferConfig) {
+	if t.PartialFiles == "" {
+		t.PartialFiles = parent.PartialFiles
+	}
+	if t.Resume == "" {
+		t.Resume = parent.Resume
+	}
+	if t.Verify == "" {
+		t.Verify = parent.Verify
+	}
+	if t.MaxAttempts <= 0 {
+		t.MaxAttempts = parent.MaxAttempts
+	}
+	if t.Conflict == "" {
+		t.Conflict = parent.Conflict
+	}
+	if t.Preserve == nil {
+		t.Preserve = parent.Preserve
+	}
+	if t.Delta == "" {
+		t.Delta = parent.Delta
+	}
+}
+
+func (t *TransferConfig) validate() error {
+	switch t.PartialFiles {
+	case PartialFilesDelete, PartialFilesKeep:
+	default:
+		return fmt.Errorf("transfer.partialFiles must be %q or %q, got %q", PartialFilesDelete, PartialFilesKeep, t.PartialFiles)
+	}
+	switch t.Resume {
+	case ResumeTail, ResumeSize, ResumeOff:
+	default:
+		return fmt.Errorf("transfer.resume must be %q, %q or %q, got %q", ResumeTail, ResumeSize, ResumeOff, t.Resume)
+	}
+	switch t.Verify {
+	case VerifyOff, VerifySHA256:
+	default:
+		return fmt.Errorf("transfer.verify must be %q or %q, got %q", VerifyOff, VerifySHA256, t.Verify)
+	}
+	switch t.Conflict {
+	case ConflictAsk, ConflictOverwrite, ConflictSkip, ConflictRename, ConflictNewer, ConflictSize:
+	default:
+		return fmt.Errorf("transfer.conflict must be one of ask, overwrite, skip, rename, newer or size, got %q", t.Conflict)
+	}
+	for _, p := range t.Preserve {
+		if p != PreserveTimes && p != PreserveMode {
+			return fmt.Errorf("transfer.preserve accepts %q and %q, got %q", PreserveTimes, PreserveMode, p)
+		}
+	}
+	switch t.Delta {
+	case DeltaOff, DeltaOn:
+	default:
+		return fmt.Errorf("transfer.delta must be %q or %q, got %q", DeltaOff, DeltaOn, t.Delta)
+	}
+	return nil
+}
+
+// KeepPartialFiles reports whether canceled transfers leave their partial
+// destination file behind.
+func (cfg *Config) KeepPartialFiles() bool {
+	return cfg.Transfer.PartialFiles == PartialFilesKeep
+}
+
+// ResumeTransfers reports whether partial destinations are continued rather
+// than overwritten.
+func (cfg *Config) ResumeTransfers() bool {
+	return cfg.Transfer.Resume != ResumeOff
+}
+
+// VerifyResume reports whether the bytes before the resume point are compared
+// with the source before continuing.
+func (cfg *Config) VerifyResume() bool {
+	return cfg.Transfer.Resume == ResumeTail
+}
+
+// VerifyChecksums reports whether transferred files are checked against their
+// source by SHA-256.
+func (cfg *Config) VerifyChecksums() bool {
+	return cfg.Transfer.Verify == VerifySHA256
+}
+
+// MaxAttempts is how many times a chunk is tried before its transfer fails.
+func (cfg *Config) MaxAttempts() int {
+	return clampInt(cfg.Transfer.MaxAttempts, 1, 100)
+}
+
+// Preserves reports whether the given metadata, PreserveTimes or
+// PreserveMode, is copied to transferred files.
+func (cfg *Config) Preserves(what string) bool {
+	return slices.Contains(cfg.Transfer.Preserve, what)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	WatchDeleteOn  = "on"
+	WatchDeleteOff = "off"
+
+	defaultWatchDebounce = 500 * time.Millisecond
+)
+
+// WatchConfig controls watch mode, which uploads local changes as they
+// happen. DebounceMs is how long a directory has to stay quiet before its
+// changes are sent. Delete set to "on" also removes files
+// from the server when they are deleted locally; it is "off" by default.
+type WatchConfig struct {
+	DebounceMs int    `yaml:"debounceMs"`
+	Delete     string `yaml:"delete"`
+}
+
+func (w *WatchConfig) applyDefaults() {
+	if w.DebounceMs <= 0 {
+		w.DebounceMs = int(defaultWatchDebounce / time.Millisecond)
+	}
+	if w.Delete == "" {
+		w.Delete = WatchDeleteOff
+	}
+}
+
+func (w *WatchConfig) inherit(parent WatchConfig) {
+	if w.DebounceMs <= 0 {
+		w.DebounceMs = parent.DebounceMs
+	}
+	if w.Delete == "" {
+		w.Delete = parent.Delete
+	}
+}
+
+func (w *WatchConfig) validate() error {
+	switch w.Delete {
+	case WatchDeleteOn, WatchDeleteOff:
+	default:
+		return fmt.Errorf("watch.delete must be %q or %q, got %q", WatchDeleteOn, WatchDeleteOff, w.Delete)
+	}
+	return nil
+}
+
+// WatchDebounce is how long watch mode waits for changes to settle, between
+// 50ms and a minute.
+func (cfg *Config) WatchDebounce() time.Duration {
+	return time.Duration(clampInt(cfg.Watch.DebounceMs, 50, 60000)) * time.Millisecond
+}
+
+// WatchDeletes reports whether watch mode mirrors local deletes.
+func (cfg *Config) WatchDeletes() bool {
+	return cfg.Watch.Delete == WatchDeleteOn
+}
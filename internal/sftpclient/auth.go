@@ -0,0 +1,190 @@
+package sftpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+// dialAgent connects to the ssh-agent listening on socket. It is a variable so
+// tests can swap in an in-process agent such as agent.NewKeyring().
+var dialAgent = func(socket string) (agent.Agent, io.Closer, error) {
+	if socket == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// authMethods builds the SSH auth methods in the order they should be tried:
+// ssh-agent identities, configured private keys, the password (prompted for
+// when none is configured), then keyboard-interactive. The returned closer
+// releases the agent connection, if one was opened.
+func authMethods(cfg *config.Endpoint, prompter Prompter, creds *credentials) ([]ssh.AuthMethod, io.Closer, error) {
+	var methods []ssh.AuthMethod
+	var agentConn io.Closer
+	var agentErr error
+	if cfg.Agent {
+		ag, conn, err := dialAgent(cfg.AgentSocket)
+		if err != nil {
+			agentErr = fmt.Errorf("connect ssh-agent: %w", err)
+		} else {
+			agentConn = conn
+			methods = append(methods, agentAuth(ag))
+		}
+	}
+	if len(cfg.Keys) > 0 {
+		signers, err := loadSigners(cfg.Keys)
+		if err != nil {
+			closeQuietly(agentConn)
+			return nil, nil, err
+		}
+		if len(signers) > 0 {
+			methods = append(methods, ssh.PublicKeys(signers...))
+		}
+	}
+	if cfg.Password != "" {
+		methods = append(methods, ssh.Password(cfg.Password))
+	} else if prompter != nil {
+		methods = append(methods, ssh.PasswordCallback(passwordPrompt(cfg, prompter, creds)))
+	}
+	if cfg.KeyboardInteractive && prompter != nil {
+		methods = append(methods, ssh.KeyboardInteractive(challengeHandler(cfg.Password, prompter)))
+	}
+	if len(methods) == 0 {
+		if agentErr != nil {
+			return nil, nil, agentErr
+		}
+		return nil, nil, errors.New("no ssh auth methods configured")
+	}
+	return methods, agentConn, nil
+}
+
+// agentAuth offers every identity held by the agent.
+func agentAuth(ag agent.Agent) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(ag.Signers)
+}
+
+func passwordPrompt(cfg *config.Endpoint, prompter Prompter, creds *credentials) func() (string, error) {
+	key := address(cfg)
+	return func() (string, error) {
+		if password, ok := creds.password(key); ok {
+			return password, nil
+		}
+		answers, err := prompter.Challenge("Password", fmt.Sprintf("%s@%s", cfg.User, cfg.Host), []string{"Password:"}, []bool{false})
+		if err != nil {
+			return "", err
+		}
+		if len(answers) == 0 {
+			return "", errors.New("no password entered")
+		}
+		creds.remember(key, answers[0])
+		return answers[0], nil
+	}
+}
+
+// credentials remembers passwords typed at the prompt, keyed by address, so
+// reconnects do not ask again.
+type credentials struct {
+	mu        sync.Mutex
+	passwords map[string]string
+}
+
+func newCredentials() *credentials {
+	return &credentials{passwords: make(map[string]string)}
+}
+
+func (c *credentials) password(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	password, ok := c.passwords[key]
+	return password, ok
+}
+
+func (c *credentials) remember(key, password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.passwords[key] = password
+}
+
+// forget drops the password for key, which the server just rejected.
+func (c *credentials) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.passwords, key)
+}
+
+// challengeHandler forwards keyboard-interactive challenges to the prompter.
+// A lone masked "password" question is answered from the config when a
+// password is set, since many servers route plain password auth through
+// keyboard-interactive.
+func challengeHandler(password string, prompter Prompter) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return nil, nil
+		}
+		if password != "" && len(questions) == 1 && !echos[0] && strings.Contains(strings.ToLower(questions[0]), "password") {
+			return []string{password}, nil
+		}
+		return prompter.Challenge(name, instruction, questions, echos)
+	}
+}
+
+func loadSigners(keys []config.KeyConfig) ([]ssh.Signer, error) {
+	signers := make([]ssh.Signer, 0, len(keys))
+	for _, key := range keys {
+		signer, err := loadSigner(key)
+		if err != nil {
+			if key.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("load key %s: %w", key.Path, err)
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+func loadSigner(key config.KeyConfig) (ssh.Signer, error) {
+	data, err := os.ReadFile(key.Path)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err == nil {
+		return signer, nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if !errors.As(err, &missing) {
+		return nil, err
+	}
+	if key.Passphrase == "" {
+		return nil, errors.New("key is encrypted and no passphrase is configured")
+	}
+	return ssh.ParsePrivateKeyWithPassphrase(data, []byte(key.Passphrase))
+}
+
+func closeQuietly(c io.Closer) {
+	if c != nil {
+		c.Close()
+	}
+}
+
+// closeAll closes closers in reverse order of acquisition.
+func closeAll(closers []io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		closeQuietly(closers[i])
+	}
+}
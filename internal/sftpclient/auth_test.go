@@ -0,0 +1,223 @@
+package sftpclient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+// testServer is an in-process SSH server that accepts a single public key.
+// Clients that authenticate may open direct-tcpip channels through it, which
+// is enough to act as a jump host, and, when it has a handler, run the sftp
+// subsystem.
+type testServer struct {
+	addr    string
+	hostKey ssh.Signer
+	sftp    func(io.ReadWriter)
+}
+
+func newTestServer(t *testing.T, user string, authorized ssh.PublicKey) *testServer {
+	t.Helper()
+	return newSFTPTestServer(t, user, authorized, nil)
+}
+
+// newSFTPTestServer starts a testServer that hands sftp subsystem channels
+// to handler.
+func newSFTPTestServer(t *testing.T, user string, authorized ssh.PublicKey, handler func(io.ReadWriter)) *testServer {
+	t.Helper()
+	hostKey := newTestSigner(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &testServer{addr: ln.Addr().String(), hostKey: hostKey, sftp: handler}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == user && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}
+	cfg.AddHostKey(hostKey)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn, cfg)
+		}
+	}()
+	return srv
+}
+
+func (s *testServer) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+	for ch := range chans {
+		if ch.ChannelType() == "session" && s.sftp != nil {
+			go s.serveSubsystem(ch)
+			continue
+		}
+		if ch.ChannelType() != "direct-tcpip" {
+			ch.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(ch.ExtraData(), &target); err != nil {
+			ch.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			ch.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, creqs, err := ch.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(creqs)
+		go func() {
+			io.Copy(upstream, channel)
+			upstream.Close()
+		}()
+		go func() {
+			io.Copy(channel, upstream)
+			channel.Close()
+		}()
+	}
+}
+
+// serveSubsystem runs the sftp handler once the client asks for the
+// subsystem, and refuses anything else.
+func (s *testServer) serveSubsystem(ch ssh.NewChannel) {
+	channel, reqs, err := ch.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range reqs {
+		var name struct{ Name string }
+		if req.Type != "subsystem" || ssh.Unmarshal(req.Payload, &name) != nil || name.Name != "sftp" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+		go ssh.DiscardRequests(reqs)
+		s.sftp(channel)
+		return
+	}
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestAgentAuth(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	signers, err := keyring.Signers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, "alice", signers[0].PublicKey())
+
+	orig := dialAgent
+	t.Cleanup(func() { dialAgent = orig })
+	dialAgent = func(socket string) (agent.Agent, io.Closer, error) {
+		if socket != "test.sock" {
+			t.Errorf("dialAgent socket = %q, want test.sock", socket)
+		}
+		return keyring, io.NopCloser(nil), nil
+	}
+
+	endpoint := &config.Endpoint{User: "alice", Agent: true, AgentSocket: "test.sock"}
+	auth, closer, err := authMethods(endpoint, nil, newCredentials())
+	if err != nil {
+		t.Fatalf("authMethods: %v", err)
+	}
+	defer closeQuietly(closer)
+
+	client, err := ssh.Dial("tcp", srv.addr, &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(srv.hostKey.PublicKey()),
+	})
+	if err != nil {
+		t.Fatalf("dial with agent identities: %v", err)
+	}
+	client.Close()
+}
+
+func TestAgentAuthRejectsUnknownIdentity(t *testing.T) {
+	srv := newTestServer(t, "alice", newTestSigner(t).PublicKey())
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	orig := dialAgent
+	t.Cleanup(func() { dialAgent = orig })
+	dialAgent = func(string) (agent.Agent, io.Closer, error) {
+		return keyring, io.NopCloser(nil), nil
+	}
+
+	auth, closer, err := authMethods(&config.Endpoint{User: "alice", Agent: true}, nil, newCredentials())
+	if err != nil {
+		t.Fatalf("authMethods: %v", err)
+	}
+	defer closeQuietly(closer)
+
+	_, err = ssh.Dial("tcp", srv.addr, &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(srv.hostKey.PublicKey()),
+	})
+	if err == nil {
+		t.Fatal("dial succeeded with an identity the server does not authorize")
+	}
+}
@@ -0,0 +1,290 @@
+package sftpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Checksum returns the SHA-256 digest of the remote file at path. The server
+// computes it when it can, through the SFTP check-file extension or a
+// sha256sum exec; otherwise the file is read back over SFTP and hashed
+// locally. Unless throttle is nil, the read-back calls it with the size of
+// every chunk before hashing it, so that a bandwidth limit covers it too.
+func (c *Client) Checksum(path string, throttle func(n int) error) ([]byte, error) {
+	c.mu.RLock()
+	sess := c.session
+	c.mu.RUnlock()
+	if sess == nil {
+		return nil, ErrNotConnected
+	}
+	return sess.checksum(path, throttle)
+}
+
+// hashSupport remembers which server-side hashing methods failed on a
+// session so they are not retried for every file.
+type hashSupport struct {
+	noCheckFile atomic.Bool
+	noExec      atomic.Bool
+	noSplit     atomic.Bool
+}
+
+func (s *session) checksum(path string, throttle func(n int) error) ([]byte, error) {
+	if _, ok := s.sftp.HasExtension("check-file"); ok && !s.hashes.noCheckFile.Load() {
+		sum, err := checkFile(s.ssh, path)
+		if err == nil {
+			return sum, nil
+		}
+		s.hashes.noCheckFile.Store(true)
+	}
+	if !s.hashes.noExec.Load() {
+		sum, err := s.execSHA256(path)
+		if err == nil {
+			return sum, nil
+		}
+		if unusableExec(err) {
+			s.hashes.noExec.Store(true)
+		}
+	}
+	f, err := s.sftp.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	var w io.Writer = h
+	if throttle != nil {
+		w = throttledWriter{w: h, throttle: throttle}
+	}
+	if _, err := f.WriteTo(w); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// throttledWriter calls throttle before passing each write on to w.
+type throttledWriter struct {
+	w        io.Writer
+	throttle func(n int) error
+}
+
+func (t throttledWriter) Write(p []byte) (int, error) {
+	if err := t.throttle(len(p)); err != nil {
+		return 0, err
+	}
+	return t.w.Write(p)
+}
+
+// BlockChecksums returns the SHA-256 of every blockSize block of the remote
+// file at path, the last one possibly shorter. The server hashes the blocks
+// when it has GNU split; otherwise they are read back over SFTP. Since a
+// wrong block hash makes a delta copy skip data, server-side hashes are only
+// used once the first and last block read over SFTP agree with them.
+func (c *Client) BlockChecksums(path string, blockSize int64) ([][]byte, error) {
+	c.mu.RLock()
+	sess := c.session
+	c.mu.RUnlock()
+	if sess == nil {
+		return nil, ErrNotConnected
+	}
+	info, err := sess.sftp.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	blocks := int((info.Size() + blockSize - 1) / blockSize)
+	if !sess.hashes.noSplit.Load() {
+		sums, err := sess.execBlockSHA256(path, info, blockSize)
+		if err == nil && len(sums) != blocks {
+			err = errOtherFile
+		}
+		if err == nil {
+			err = sess.spotCheck(path, blockSize, sums)
+		}
+		if err == nil {
+			return sums, nil
+		}
+		if unusableExec(err) {
+			sess.hashes.noSplit.Store(true)
+		}
+	}
+	f, err := sess.sftp.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sums := make([][]byte, 0, blocks)
+	buf := make([]byte, blockSize)
+	for off := int64(0); off < info.Size(); off += blockSize {
+		sum, err := blockSHA256(f, buf, off)
+		if err != nil {
+			return nil, err
+		}
+		sums = append(sums, sum)
+	}
+	return sums, nil
+}
+
+// spotCheck compares the first and last of sums with the blocks as read over
+// SFTP.
+func (s *session) spotCheck(path string, blockSize int64, sums [][]byte) error {
+	if len(sums) == 0 {
+		return nil
+	}
+	f, err := s.sftp.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	buf := make([]byte, blockSize)
+	for _, i := range []int{0, len(sums) - 1} {
+		sum, err := blockSHA256(f, buf, int64(i)*blockSize)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(sum, sums[i]) {
+			return errOtherFile
+		}
+	}
+	return nil
+}
+
+func blockSHA256(r io.ReaderAt, buf []byte, off int64) ([]byte, error) {
+	n, err := r.ReadAt(buf, off)
+	if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return sum[:], nil
+}
+
+// execBlockSHA256 has GNU split pipe each block of path through sha256sum.
+func (s *session) execBlockSHA256(path string, info os.FileInfo, blockSize int64) ([][]byte, error) {
+	out, err := s.execChecked(path, info, fmt.Sprintf("split -b %d --filter=sha256sum -- %s", blockSize, shellQuote(path)))
+	if err != nil {
+		return nil, err
+	}
+	var sums [][]byte
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("unexpected split output %q", line)
+		}
+		sums = append(sums, sum)
+	}
+	return sums, nil
+}
+
+// errOtherFile means a shell command saw a different file at a path than
+// SFTP does, as happens when the SFTP server is chrooted and the shell is
+// not.
+var errOtherFile = errors.New("shell and sftp see different files")
+
+// execSHA256 runs sha256sum on the server.
+func (s *session) execSHA256(path string) ([]byte, error) {
+	info, err := s.sftp.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.execChecked(path, info, "sha256sum -b -- "+shellQuote(path))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return nil, errors.New("empty sha256sum output")
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(fields[0], "\\"))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("unexpected sha256sum output %q", out)
+	}
+	return sum, nil
+}
+
+// execChecked runs command about path on the server and returns its output.
+// The shell resolves path in its own namespace, so the file's size and
+// modification time are first checked against info, what SFTP reports.
+func (s *session) execChecked(path string, info os.FileInfo, command string) (string, error) {
+	sess, err := s.ssh.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer sess.Close()
+	out, err := sess.Output("stat -L -c '%s %Y' -- " + shellQuote(path) + " && " + command)
+	if err != nil {
+		return "", err
+	}
+	head, rest, _ := strings.Cut(string(out), "\n")
+	want := strconv.FormatInt(info.Size(), 10) + " " + strconv.FormatInt(info.ModTime().Unix(), 10)
+	if strings.TrimSpace(head) != want {
+		return "", errOtherFile
+	}
+	return rest, nil
+}
+
+// unusableExec reports whether err means a server-side hashing command will
+// never work on this session: the command is missing or sees other files.
+// Anything else, such as a dropped channel, may pass on the next file.
+func unusableExec(err error) bool {
+	if errors.Is(err, errOtherFile) {
+		return true
+	}
+	var exitErr *ssh.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitStatus() == 127
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// checkFile asks the server for the file's SHA-256 with a check-file-name
+// request, part of the check-file extension.
+func checkFile(client *ssh.Client, path string) ([]byte, error) {
+	raw, err := openRawSFTP(client)
+	if err != nil {
+		return nil, err
+	}
+	defer raw.close()
+
+	var req []byte
+	req = appendString(req, "check-file-name")
+	req = appendString(req, path)
+	req = appendString(req, "sha256")
+	req = binary.BigEndian.AppendUint64(req, 0)
+	req = binary.BigEndian.AppendUint64(req, 0)
+	req = binary.BigEndian.AppendUint32(req, 0)
+	typ, data, err := raw.request(fxpExtended, req)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case fxpExtendedReply:
+	case fxpStatus:
+		return nil, errors.New("check-file refused")
+	default:
+		return nil, fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+	// "check-file", algorithm, then the digest.
+	for range 2 {
+		if _, data, err = readString(data); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) != sha256.Size {
+		return nil, fmt.Errorf("check-file returned %d byte digest", len(data))
+	}
+	return bytes.Clone(data), nil
+}
@@ -0,0 +1,70 @@
+package sftpclient
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Range is a span of bytes within a file.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// CanCopyData reports whether the server copies data between its own files
+// through the copy-data extension (OpenSSH 9.0 and later).
+func (c *Client) CanCopyData() bool {
+	c.mu.RLock()
+	sess := c.session
+	c.mu.RUnlock()
+	if sess == nil {
+		return false
+	}
+	_, ok := sess.sftp.HasExtension("copy-data")
+	return ok
+}
+
+// CopyData copies each range of src to the same offset in dst on the server,
+// so the data never crosses the connection. dst must already exist.
+func (c *Client) CopyData(src, dst string, ranges []Range) error {
+	c.mu.RLock()
+	sess := c.session
+	c.mu.RUnlock()
+	if sess == nil {
+		return ErrNotConnected
+	}
+	if _, ok := sess.sftp.HasExtension("copy-data"); !ok {
+		return errors.New("server does not support copy-data")
+	}
+	raw, err := openRawSFTP(sess.ssh)
+	if err != nil {
+		return err
+	}
+	defer raw.close()
+
+	from, err := raw.open(src, fxfRead)
+	if err != nil {
+		return err
+	}
+	defer raw.closeHandle(from)
+	to, err := raw.open(dst, fxfWrite)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		req := appendString(nil, "copy-data")
+		req = appendString(req, from)
+		req = binary.BigEndian.AppendUint64(req, uint64(r.Offset))
+		req = binary.BigEndian.AppendUint64(req, uint64(r.Length))
+		req = appendString(req, to)
+		req = binary.BigEndian.AppendUint64(req, uint64(r.Offset))
+		if err = raw.status(fxpExtended, req); err != nil {
+			break
+		}
+	}
+	if cerr := raw.closeHandle(to); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package sftpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+// dialer holds everything needed to (re)establish a session: the resolved
+// config, host key store, prompter and passwords typed so far.
+type dialer struct {
+	cfg      *config.Config
+	hosts    *hostKeys
+	prompter Prompter
+	creds    *credentials
+}
+
+func newDialer(cfg *config.Config, prompter Prompter) *dialer {
+	return &dialer{
+		cfg:      cfg,
+		hosts:    newHostKeys(cfg),
+		prompter: prompter,
+		creds:    newCredentials(),
+	}
+}
+
+// dial opens a fresh SSH connection chain and SFTP session.
+func (d *dialer) dial() (*session, error) {
+	sshConn, closers, err := d.dialChain()
+	if err != nil {
+		var authErr *authError
+		if errors.As(err, &authErr) {
+			d.creds.forget(authErr.addr)
+		}
+		return nil, err
+	}
+
+	// The subsystem handshake can hang on a dead link just like SSH's.
+	guard := newHandshakeGuard(d.cfg.ConnectTimeout())
+	guard.arm(sshConn)
+	sftpConn, err := dialSFTP(sshConn, d.cfg)
+	if guard.disarm() && err == nil {
+		sftpConn.Close()
+		err = fmt.Errorf("timed out after %s", d.cfg.ConnectTimeout())
+	}
+	if err != nil {
+		sshConn.Close()
+		closeAll(closers)
+		return nil, fmt.Errorf("create sftp client: %w", err)
+	}
+	return &session{sftp: sftpConn, ssh: sshConn, closers: closers}, nil
+}
+
+// dialChain connects to the target host, tunnelling through each configured
+// jump host in turn. The returned closers hold the jump connections and agent
+// sockets; they must be closed after the target connection.
+func (d *dialer) dialChain() (*ssh.Client, []io.Closer, error) {
+	hops := make([]*config.Endpoint, 0, len(d.cfg.ProxyJump)+1)
+	for i := range d.cfg.ProxyJump {
+		hops = append(hops, &d.cfg.ProxyJump[i].Endpoint)
+	}
+	hops = append(hops, &d.cfg.Endpoint)
+
+	var closers []io.Closer
+	var via *ssh.Client
+	for i, hop := range hops {
+		client, agentConn, err := d.dialHop(via, hop)
+		if err != nil {
+			if via != nil {
+				closers = append(closers, via)
+			}
+			closeAll(closers)
+			if i < len(hops)-1 {
+				return nil, nil, fmt.Errorf("jump host %s: %w", hop.Host, err)
+			}
+			return nil, nil, err
+		}
+		if via != nil {
+			closers = append(closers, via)
+		}
+		if agentConn != nil {
+			closers = append(closers, agentConn)
+		}
+		via = client
+	}
+	return via, closers, nil
+}
+
+func (d *dialer) dialHop(via *ssh.Client, hop *config.Endpoint) (*ssh.Client, io.Closer, error) {
+	guard := newHandshakeGuard(d.cfg.ConnectTimeout())
+	prompter := guard.wrap(d.prompter)
+	auth, agentConn, err := authMethods(hop, prompter, d.creds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addr := address(hop)
+	checkHostKey := d.hosts.callback(prompter)
+	var verified atomic.Bool
+	sshConfig := &ssh.ClientConfig{
+		User: hop.User,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := checkHostKey(hostname, remote, key)
+			verified.Store(err == nil)
+			return err
+		},
+		HostKeyAlgorithms: d.hosts.algorithms(addr),
+		Timeout:           d.cfg.ConnectTimeout(),
+	}
+	if ciphers := d.cfg.SSHCiphers(); len(ciphers) > 0 {
+		sshConfig.Config.Ciphers = ciphers
+	}
+
+	client, err := connect(via, addr, sshConfig, guard)
+	if err != nil {
+		closeQuietly(agentConn)
+		if verified.Load() && !isTransportError(err) {
+			err = &authError{addr: addr, err: err}
+		}
+		return nil, nil, fmt.Errorf("dial ssh: %w", err)
+	}
+	return client, agentConn, nil
+}
+
+// authError is a handshake that failed after the host key was accepted for
+// a reason other than the connection breaking, which leaves authentication.
+type authError struct {
+	addr string
+	err  error
+}
+
+func (e *authError) Error() string { return e.err.Error() }
+func (e *authError) Unwrap() error { return e.err }
+
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// connect opens an SSH connection to addr, either directly or through a
+// direct-tcpip channel on via, and abandons handshakes that outlive the
+// connect timeout.
+func connect(via *ssh.Client, addr string, sshConfig *ssh.ClientConfig, guard *handshakeGuard) (*ssh.Client, error) {
+	var conn net.Conn
+	var err error
+	if via == nil {
+		conn, err = net.DialTimeout("tcp", addr, sshConfig.Timeout)
+	} else {
+		conn, err = dialVia(via, addr, sshConfig.Timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	guard.arm(conn)
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if guard.disarm() {
+		if err == nil {
+			c.Close()
+		}
+		return nil, fmt.Errorf("ssh handshake with %s timed out after %s", addr, guard.timeout)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
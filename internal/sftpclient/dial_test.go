@@ -0,0 +1,188 @@
+package sftpclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+// testKey writes a fresh unencrypted private key to dir and returns its
+// config entry and public half.
+func testKey(t *testing.T, dir, name string) (config.KeyConfig, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.KeyConfig{Path: path}, sshPub
+}
+
+func testEndpoint(t *testing.T, addr, user string, key config.KeyConfig) config.Endpoint {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return config.Endpoint{Host: host, Port: port, User: user, Keys: []config.KeyConfig{key}}
+}
+
+func writeKnownHosts(t *testing.T, dir string, entries map[string]ssh.PublicKey) string {
+	t.Helper()
+	var lines []string
+	for addr, key := range entries {
+		lines = append(lines, knownhosts.Line([]string{knownhosts.Normalize(addr)}, key))
+	}
+	path := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type jumpFixture struct {
+	dir    string
+	jump   *testServer
+	target *testServer
+	cfg    *config.Config
+}
+
+// newJumpFixture starts a bastion that only admits "bastion" with its own key
+// and a target that only admits "alice" with another, and configures a
+// two-hop connection that knows both host keys.
+func newJumpFixture(t *testing.T) *jumpFixture {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	jumpKey, jumpPub := testKey(t, dir, "jump_key")
+	targetKey, targetPub := testKey(t, dir, "target_key")
+	jump := newTestServer(t, "bastion", jumpPub)
+	target := newTestServer(t, "alice", targetPub)
+
+	cfg := &config.Config{
+		Endpoint:          testEndpoint(t, target.addr, "alice", targetKey),
+		ProxyJump:         config.JumpHosts{{Endpoint: testEndpoint(t, jump.addr, "bastion", jumpKey)}},
+		ConnectTimeoutSec: 5,
+	}
+	cfg.KnownHosts = []string{writeKnownHosts(t, dir, map[string]ssh.PublicKey{
+		jump.addr:   jump.hostKey.PublicKey(),
+		target.addr: target.hostKey.PublicKey(),
+	})}
+	return &jumpFixture{dir: dir, jump: jump, target: target, cfg: cfg}
+}
+
+func (f *jumpFixture) dial(t *testing.T) error {
+	t.Helper()
+	client, closers, err := newDialer(f.cfg, nil).dialChain()
+	if err != nil {
+		return err
+	}
+	client.Close()
+	closeAll(closers)
+	return nil
+}
+
+func TestDialChainThroughJumpHost(t *testing.T) {
+	f := newJumpFixture(t)
+	if err := f.dial(t); err != nil {
+		t.Fatalf("dial through jump host: %v", err)
+	}
+}
+
+func TestDialChainJumpHostUsesItsOwnAuth(t *testing.T) {
+	f := newJumpFixture(t)
+	f.cfg.ProxyJump[0].Keys = f.cfg.Keys
+
+	err := f.dial(t)
+	if err == nil {
+		t.Fatal("jump host accepted the target's key")
+	}
+	var authErr *authError
+	if !errors.As(err, &authErr) || authErr.addr != f.jump.addr {
+		t.Fatalf("error = %v, want an authentication failure at the jump host %s", err, f.jump.addr)
+	}
+}
+
+func TestDialChainChecksEachHostKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		known   func(f *jumpFixture) map[string]ssh.PublicKey
+		wantErr []string
+	}{
+		{
+			name: "jump host key mismatch",
+			known: func(f *jumpFixture) map[string]ssh.PublicKey {
+				return map[string]ssh.PublicKey{
+					f.jump.addr:   f.target.hostKey.PublicKey(),
+					f.target.addr: f.target.hostKey.PublicKey(),
+				}
+			},
+			wantErr: []string{"jump host", "host key mismatch"},
+		},
+		{
+			name: "target host key mismatch",
+			known: func(f *jumpFixture) map[string]ssh.PublicKey {
+				return map[string]ssh.PublicKey{
+					f.jump.addr:   f.jump.hostKey.PublicKey(),
+					f.target.addr: f.jump.hostKey.PublicKey(),
+				}
+			},
+			wantErr: []string{"host key mismatch"},
+		},
+		{
+			name: "target host unknown",
+			known: func(f *jumpFixture) map[string]ssh.PublicKey {
+				return map[string]ssh.PublicKey{f.jump.addr: f.jump.hostKey.PublicKey()}
+			},
+			wantErr: []string{"not in known_hosts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newJumpFixture(t)
+			f.cfg.KnownHosts = []string{writeKnownHosts(t, f.dir, tt.known(f))}
+
+			err := f.dial(t)
+			if err == nil {
+				t.Fatal("dial succeeded")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Fatalf("error = %v, want it to mention %q", err, want)
+				}
+			}
+			var authErr *authError
+			if errors.As(err, &authErr) {
+				t.Fatalf("host key failure reported as an authentication failure: %v", err)
+			}
+			if strings.Contains(tt.name, "target") && strings.Contains(err.Error(), "jump host") {
+				t.Fatalf("target failure reported as a jump host failure: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package sftpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+// Prompter lets the SSH handshake ask the user for decisions and secrets it
+// cannot obtain on its own. Implementations block until the user answers.
+type Prompter interface {
+	Confirm(title, message string) (bool, error)
+	Challenge(name, instruction string, questions []string, echos []bool) ([]string, error)
+}
+
+// hostKeys verifies server keys against the user's known_hosts files plus the
+// termftp-specific one, which is where keys accepted on first use are stored.
+type hostKeys struct {
+	files []string
+	store string
+}
+
+func newHostKeys(cfg *config.Config) *hostKeys {
+	store := config.KnownHostsPath()
+	return &hostKeys{
+		files: append(append([]string(nil), cfg.KnownHosts...), store),
+		store: store,
+	}
+}
+
+// callback returns a HostKeyCallback that asks prompter about unknown hosts.
+func (h *hostKeys) callback(prompter Prompter) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return h.verify(hostname, remote, key, prompter)
+	}
+}
+
+func (h *hostKeys) verify(hostname string, remote net.Addr, key ssh.PublicKey, prompter Prompter) error {
+	db, err := h.load()
+	if err != nil {
+		return err
+	}
+	if db != nil {
+		err := db(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			known := keyErr.Want[0]
+			return fmt.Errorf("host key mismatch for %s: got %s %s, %s:%d has %s; possible man-in-the-middle attack",
+				hostname, key.Type(), ssh.FingerprintSHA256(key), known.Filename, known.Line, ssh.FingerprintSHA256(known.Key))
+		}
+	}
+	return h.trustOnFirstUse(hostname, remote, key, prompter)
+}
+
+func (h *hostKeys) trustOnFirstUse(hostname string, remote net.Addr, key ssh.PublicKey, prompter Prompter) error {
+	if prompter == nil {
+		return fmt.Errorf("host key for %s is not in known_hosts", hostname)
+	}
+	message := fmt.Sprintf(
+		"The authenticity of host %s (%s) can't be established.\n%s key fingerprint is %s.\nAccept and remember this key?",
+		hostname, remote, key.Type(), ssh.FingerprintSHA256(key),
+	)
+	ok, err := prompter.Confirm("Unknown host key", message)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("host key for %s rejected", hostname)
+	}
+	return h.remember(hostname, remote, key)
+}
+
+func (h *hostKeys) remember(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		if ip := knownhosts.Normalize(remote.String()); ip != addresses[0] {
+			addresses = append(addresses, ip)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(h.store), 0o700); err != nil {
+		return fmt.Errorf("save host key: %w", err)
+	}
+	f, err := os.OpenFile(h.store, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("save host key: %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, knownhosts.Line(addresses, key)); err != nil {
+		return fmt.Errorf("save host key: %w", err)
+	}
+	return nil
+}
+
+// algorithms restricts negotiation to the key types already known for addr,
+// so a host with an RSA entry is not reported as mismatched when the server
+// would prefer to offer an ed25519 key.
+func (h *hostKeys) algorithms(addr string) []string {
+	db, err := h.load()
+	if err != nil || db == nil {
+		return nil
+	}
+	probe := &net.TCPAddr{IP: net.IPv4zero}
+	var keyErr *knownhosts.KeyError
+	if !errors.As(db(addr, probe, probeKey{}), &keyErr) {
+		return nil
+	}
+	var algos []string
+	seen := make(map[string]struct{})
+	for _, known := range keyErr.Want {
+		for _, algo := range algorithmsForKeyType(known.Key.Type()) {
+			if _, ok := seen[algo]; ok {
+				continue
+			}
+			seen[algo] = struct{}{}
+			algos = append(algos, algo)
+		}
+	}
+	return algos
+}
+
+func (h *hostKeys) load() (ssh.HostKeyCallback, error) {
+	var existing []string
+	for _, file := range h.files {
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			existing = append(existing, file)
+		}
+	}
+	if len(existing) == 0 {
+		return nil, nil
+	}
+	db, err := knownhosts.New(existing...)
+	if err != nil {
+		return nil, fmt.Errorf("load known_hosts: %w", err)
+	}
+	return db, nil
+}
+
+func algorithmsForKeyType(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// probeKey never matches a real key; it is only used to list known entries.
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "termftp-probe" }
+func (probeKey) Marshal() []byte                              { return []byte("termftp-probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("probe key") }
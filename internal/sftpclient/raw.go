@@ -0,0 +1,174 @@
+package sftpclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP packet types used by the raw requests below.
+const (
+	fxpInit          = 1
+	fxpVersion       = 2
+	fxpOpen          = 3
+	fxpClose         = 4
+	fxpStatus        = 101
+	fxpHandle        = 102
+	fxpExtended      = 200
+	fxpExtendedReply = 201
+)
+
+// SFTP open flags.
+const (
+	fxfRead  = 0x01
+	fxfWrite = 0x02
+)
+
+// rawSFTP speaks just enough of the SFTP protocol on a short-lived subsystem
+// channel to send the extended requests pkg/sftp has no API for.
+type rawSFTP struct {
+	sess *ssh.Session
+	w    io.Writer
+	r    io.Reader
+	id   uint32
+}
+
+func openRawSFTP(client *ssh.Client) (*rawSFTP, error) {
+	sess, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	raw := &rawSFTP{sess: sess}
+	if err := raw.init(); err != nil {
+		sess.Close()
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (s *rawSFTP) init() error {
+	var err error
+	if s.w, err = s.sess.StdinPipe(); err != nil {
+		return err
+	}
+	if s.r, err = s.sess.StdoutPipe(); err != nil {
+		return err
+	}
+	if err := s.sess.RequestSubsystem("sftp"); err != nil {
+		return err
+	}
+	if err := writePacket(s.w, fxpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, _, err := readPacket(s.r)
+	if err != nil {
+		return err
+	}
+	if typ != fxpVersion {
+		return fmt.Errorf("unexpected sftp packet %d", typ)
+	}
+	return nil
+}
+
+func (s *rawSFTP) close() error {
+	return s.sess.Close()
+}
+
+// request sends a packet of type typ with a fresh request id followed by
+// payload, and returns the reply with its id stripped.
+func (s *rawSFTP) request(typ byte, payload []byte) (byte, []byte, error) {
+	s.id++
+	req := binary.BigEndian.AppendUint32(nil, s.id)
+	if err := writePacket(s.w, typ, append(req, payload...)); err != nil {
+		return 0, nil, err
+	}
+	replyType, data, err := readPacket(s.r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != s.id {
+		return 0, nil, errors.New("unexpected sftp reply id")
+	}
+	return replyType, data[4:], nil
+}
+
+// status sends a request that is answered with a status packet and turns a
+// failure into an error.
+func (s *rawSFTP) status(typ byte, payload []byte) error {
+	replyType, data, err := s.request(typ, payload)
+	if err != nil {
+		return err
+	}
+	if replyType != fxpStatus || len(data) < 4 {
+		return fmt.Errorf("unexpected sftp packet %d", replyType)
+	}
+	if code := binary.BigEndian.Uint32(data); code != 0 {
+		msg, _, _ := readString(data[4:])
+		return fmt.Errorf("sftp status %d: %s", code, msg)
+	}
+	return nil
+}
+
+// open returns a handle for path, opened with the given SFTP flags.
+func (s *rawSFTP) open(path string, flags uint32) (string, error) {
+	req := appendString(nil, path)
+	req = binary.BigEndian.AppendUint32(req, flags)
+	req = binary.BigEndian.AppendUint32(req, 0)
+	replyType, data, err := s.request(fxpOpen, req)
+	if err != nil {
+		return "", err
+	}
+	if replyType != fxpHandle {
+		return "", fmt.Errorf("open %s: sftp packet %d", path, replyType)
+	}
+	handle, _, err := readString(data)
+	return handle, err
+}
+
+func (s *rawSFTP) closeHandle(handle string) error {
+	return s.status(fxpClose, appendString(nil, handle))
+}
+
+func writePacket(w io.Writer, typ byte, payload []byte) error {
+	var buf []byte
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)+1))
+	buf = append(buf, typ)
+	buf = append(buf, payload...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length == 0 || length > 256*1024 {
+		return 0, nil, fmt.Errorf("bad sftp packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 4 {
+		return "", nil, errors.New("short sftp string")
+	}
+	n := binary.BigEndian.Uint32(data[:4])
+	if uint32(len(data)-4) < n {
+		return "", nil, errors.New("short sftp string")
+	}
+	return string(data[4 : 4+n]), data[4+n:], nil
+}
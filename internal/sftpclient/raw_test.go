@@ -0,0 +1,215 @@
+package sftpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeSFTP is a server for the few requests rawSFTP sends, enough to check
+// that both ends agree on the packets. It advertises the copy-data and
+// check-file extensions and answers everything else as unsupported.
+type fakeSFTP struct {
+	files map[string]*os.File
+	next  int
+}
+
+const (
+	fxOK          = 0
+	fxNoSuchFile  = 2
+	fxFailure     = 4
+	fxUnsupported = 8
+)
+
+func serveFakeSFTP(rw io.ReadWriter) {
+	f := &fakeSFTP{files: map[string]*os.File{}}
+	defer func() {
+		for _, file := range f.files {
+			file.Close()
+		}
+	}()
+	for {
+		typ, data, err := readPacket(rw)
+		if err != nil {
+			return
+		}
+		if typ == fxpInit {
+			reply := binary.BigEndian.AppendUint32(nil, 3)
+			for _, ext := range []string{"copy-data", "check-file"} {
+				reply = appendString(appendString(reply, ext), "1")
+			}
+			writePacket(rw, fxpVersion, reply)
+			continue
+		}
+		if len(data) < 4 {
+			return
+		}
+		id, data := data[:4], data[4:]
+		replyType, reply := f.handle(typ, data)
+		writePacket(rw, replyType, append(bytes.Clone(id), reply...))
+	}
+}
+
+func (f *fakeSFTP) handle(typ byte, data []byte) (byte, []byte) {
+	switch typ {
+	case fxpOpen:
+		path, rest, _ := readString(data)
+		flag := os.O_RDONLY
+		if len(rest) >= 4 && binary.BigEndian.Uint32(rest)&fxfWrite != 0 {
+			flag = os.O_WRONLY
+		}
+		file, err := os.OpenFile(path, flag, 0)
+		if err != nil {
+			return statusReply(fxNoSuchFile, err.Error())
+		}
+		f.next++
+		handle := strconv.Itoa(f.next)
+		f.files[handle] = file
+		return fxpHandle, appendString(nil, handle)
+	case fxpClose:
+		handle, _, _ := readString(data)
+		file, ok := f.files[handle]
+		if !ok {
+			return statusReply(fxFailure, "bad handle")
+		}
+		delete(f.files, handle)
+		file.Close()
+		return statusReply(fxOK, "")
+	case fxpExtended:
+		name, rest, _ := readString(data)
+		switch name {
+		case "copy-data":
+			return f.copyData(rest)
+		case "check-file-name":
+			return f.checkFileName(rest)
+		}
+	}
+	return statusReply(fxUnsupported, "unsupported")
+}
+
+func (f *fakeSFTP) copyData(data []byte) (byte, []byte) {
+	from, data, _ := readString(data)
+	if len(data) < 16 {
+		return statusReply(fxFailure, "short copy-data")
+	}
+	off, length := int64(binary.BigEndian.Uint64(data)), int64(binary.BigEndian.Uint64(data[8:]))
+	to, data, _ := readString(data[16:])
+	if len(data) < 8 || f.files[from] == nil || f.files[to] == nil {
+		return statusReply(fxFailure, "bad copy-data")
+	}
+	dstOff := int64(binary.BigEndian.Uint64(data))
+	src := io.NewSectionReader(f.files[from], off, length)
+	if _, err := io.Copy(io.NewOffsetWriter(f.files[to], dstOff), src); err != nil {
+		return statusReply(fxFailure, err.Error())
+	}
+	return statusReply(fxOK, "")
+}
+
+func (f *fakeSFTP) checkFileName(data []byte) (byte, []byte) {
+	path, data, _ := readString(data)
+	algos, _, _ := readString(data)
+	if algos != "sha256" {
+		return statusReply(fxUnsupported, "algorithm")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return statusReply(fxNoSuchFile, err.Error())
+	}
+	sum := sha256.Sum256(content)
+	reply := appendString(appendString(nil, "check-file"), "sha256")
+	return fxpExtendedReply, append(reply, sum[:]...)
+}
+
+func statusReply(code uint32, msg string) (byte, []byte) {
+	reply := binary.BigEndian.AppendUint32(nil, code)
+	return fxpStatus, appendString(appendString(reply, msg), "")
+}
+
+// dialFakeSFTP connects a Client to a server running fakeSFTP.
+func dialFakeSFTP(t *testing.T) *Client {
+	t.Helper()
+	signer := newTestSigner(t)
+	srv := newSFTPTestServer(t, "alice", signer.PublicKey(), serveFakeSFTP)
+	conn, err := ssh.Dial("tcp", srv.addr, &ssh.ClientConfig{
+		User:            "alice",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.FixedHostKey(srv.hostKey.PublicKey()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+	c := &Client{session: &session{sftp: sc, ssh: conn}}
+	t.Cleanup(func() { c.session.close() })
+	return c
+}
+
+func TestCheckFile(t *testing.T) {
+	c := dialFakeSFTP(t)
+	path := filepath.Join(t.TempDir(), "file")
+	content := []byte("check-file round trip")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := checkFile(c.session.ssh, path)
+	if err != nil {
+		t.Fatalf("checkFile: %v", err)
+	}
+	if want := sha256.Sum256(content); !bytes.Equal(got, want[:]) {
+		t.Fatalf("checkFile = %x, want %x", got, want)
+	}
+	if _, err := checkFile(c.session.ssh, path+".missing"); err == nil {
+		t.Fatal("checkFile of a missing file succeeded")
+	}
+}
+
+func TestCopyData(t *testing.T) {
+	c := dialFakeSFTP(t)
+	if !c.CanCopyData() {
+		t.Fatal("copy-data not detected")
+	}
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.WriteFile(src, []byte("0123456789abcdef"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("................"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.CopyData(src, dst, []Range{{Offset: 0, Length: 4}, {Offset: 10, Length: 6}}); err != nil {
+		t.Fatalf("CopyData: %v", err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0123......abcdef"; string(got) != want {
+		t.Fatalf("dst = %q, want %q", got, want)
+	}
+}
+
+func TestCopyDataMissingTarget(t *testing.T) {
+	c := dialFakeSFTP(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CopyData(src, filepath.Join(dir, "missing"), []Range{{Length: 4}}); err == nil {
+		t.Fatal("CopyData into a missing file succeeded")
+	}
+}
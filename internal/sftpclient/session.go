@@ -0,0 +1,181 @@
+package sftpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// State describes the health of the connection.
+type State int
+
+const (
+	StateConnected State = iota
+	StateReconnecting
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	default:
+		return "closed"
+	}
+}
+
+// Status is a connection state together with the error that caused it, if
+// any. Attempt counts reconnect attempts since the connection was lost.
+type Status struct {
+	State   State
+	Err     error
+	Attempt int
+}
+
+// session is one live SSH connection chain with its SFTP subsystem.
+type session struct {
+	sftp    *sftp.Client
+	ssh     *ssh.Client
+	closers []io.Closer
+	hashes  hashSupport
+}
+
+// close tears the session down. The SSH connection goes first so that
+// closing the SFTP client cannot block on a dead link.
+func (s *session) close() error {
+	err := s.ssh.Close()
+	s.sftp.Close()
+	closeAll(s.closers)
+	return err
+}
+
+// monitor waits for sess to die, then reconnects until it succeeds or the
+// client is closed.
+func (c *Client) monitor(sess *session) {
+	err := c.watch(sess)
+	if err == nil || !c.drop(sess, err) {
+		return
+	}
+	sess.close()
+	c.reconnect()
+}
+
+// watch blocks until the session fails: the SSH connection or SFTP subsystem
+// exits, or keepalives go unanswered. It returns nil once the client is
+// closed, which tears the session down itself.
+func (c *Client) watch(sess *session) error {
+	lost := make(chan error, 2)
+	go func() { lost <- sess.ssh.Wait() }()
+	go func() { lost <- sess.sftp.Wait() }()
+
+	interval := c.dialer.cfg.KeepaliveInterval()
+	maxMissed := max(c.dialer.cfg.KeepaliveMaxMissed, 1)
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	missed := 0
+	for {
+		select {
+		case <-c.done:
+			return nil
+		case err := <-lost:
+			if err == nil {
+				err = errors.New("connection closed by server")
+			}
+			return err
+		case <-tick:
+			if err := keepalive(sess.ssh, interval); err != nil {
+				missed++
+				if missed >= maxMissed {
+					return fmt.Errorf("no keepalive response after %d attempts: %w", missed, err)
+				}
+				continue
+			}
+			missed = 0
+		}
+	}
+}
+
+// keepalive sends an OpenSSH keepalive request and waits up to timeout for
+// the reply; a dead link otherwise blocks SendRequest indefinitely.
+func keepalive(conn *ssh.Client, timeout time.Duration) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return errors.New("keepalive timed out")
+	}
+}
+
+// drop detaches a failed session and reports whether a reconnect should
+// follow.
+func (c *Client) drop(sess *session, err error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.session != sess {
+		return false
+	}
+	c.session = nil
+	c.setStatus(Status{State: StateReconnecting, Err: err})
+	return true
+}
+
+func (c *Client) reconnect() {
+	delay := reconnectMinDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(delay):
+		}
+		sess, err := c.dialer.dial()
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			if sess != nil {
+				sess.close()
+			}
+			return
+		}
+		if err == nil {
+			c.session = sess
+			c.setStatus(Status{State: StateConnected})
+			c.mu.Unlock()
+			go c.monitor(sess)
+			return
+		}
+		c.setStatus(Status{State: StateReconnecting, Err: err, Attempt: attempt})
+		c.mu.Unlock()
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// setStatus records status and publishes it, replacing any unread event.
+// Callers hold c.mu.
+func (c *Client) setStatus(status Status) {
+	c.status = status
+	select {
+	case <-c.events:
+	default:
+	}
+	c.events <- status
+}
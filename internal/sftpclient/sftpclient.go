@@ -2,10 +2,10 @@ package sftpclient
 
 import (
 	"errors"
-	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
@@ -16,41 +16,70 @@ import (
 const defaultSSHPort = 22
 const fallbackPacketBytes = 32 * 1024
 
+// ErrNotConnected is returned for requests made while there is no session,
+// such as during a reconnect.
+var ErrNotConnected = errors.New("remote not connected")
+
+// Client owns the SFTP session to the configured host. When the connection
+// drops it reconnects in the background; callers fetch the live session with
+// SFTP and follow connection changes through Events.
 type Client struct {
-	*sftp.Client
-	sshConn *ssh.Client
+	dialer *dialer
+	events chan Status
+	done   chan struct{}
+
+	mu      sync.RWMutex
+	session *session
+	status  Status
+	closed  bool
 }
 
-func New(cfg *config.Config) (*Client, error) {
+// New dials the configured host and opens an SFTP session. prompter is asked
+// to confirm unknown host keys and to answer keyboard-interactive challenges;
+// when it is nil unknown hosts are rejected and interactive auth is skipped.
+func New(cfg *config.Config, prompter Prompter) (*Client, error) {
 	if cfg == nil {
 		return nil, errors.New("config is nil")
 	}
 
-	sshConfig := &ssh.ClientConfig{
-		User: cfg.User,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(cfg.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
-	if ciphers := cfg.SSHCiphers(); len(ciphers) > 0 {
-		sshConfig.Config.Ciphers = ciphers
+	d := newDialer(cfg, prompter)
+	sess, err := d.dial()
+	if err != nil {
+		return nil, err
 	}
 
-	addr := address(cfg)
-
-	sshConn, err := ssh.Dial("tcp", addr, sshConfig)
-	if err != nil {
-		return nil, fmt.Errorf("dial ssh: %w", err)
+	c := &Client{
+		dialer:  d,
+		events:  make(chan Status, 1),
+		done:    make(chan struct{}),
+		session: sess,
+		status:  Status{State: StateConnected},
 	}
+	go c.monitor(sess)
+	return c, nil
+}
 
-	sftpConn, err := dialSFTP(sshConn, cfg)
-	if err != nil {
-		sshConn.Close()
-		return nil, fmt.Errorf("create sftp client: %w", err)
+// SFTP returns the current SFTP session, or nil while disconnected.
+func (c *Client) SFTP() *sftp.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.session == nil {
+		return nil
 	}
+	return c.session.sftp
+}
 
-	return &Client{Client: sftpConn, sshConn: sshConn}, nil
+// Status reports the current connection state.
+func (c *Client) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// Events delivers connection state changes. Only the latest change is kept
+// if the receiver falls behind.
+func (c *Client) Events() <-chan Status {
+	return c.events
 }
 
 func (c *Client) Close() error {
@@ -58,16 +87,22 @@ func (c *Client) Close() error {
 		return nil
 	}
 
-	var err error
-	if c.Client != nil {
-		err = c.Client.Close()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
 	}
+	c.closed = true
+	close(c.done)
+	sess := c.session
+	c.session = nil
+	c.status = Status{State: StateClosed}
+	c.mu.Unlock()
 
-	if closeErr := c.closeSSH(); err == nil {
-		err = closeErr
+	if sess == nil {
+		return nil
 	}
-
-	return err
+	return sess.close()
 }
 
 func dialSFTP(sshConn *ssh.Client, cfg *config.Config) (*sftp.Client, error) {
@@ -123,21 +158,10 @@ func max(a, b int) int {
 	return b
 }
 
-func (c *Client) closeSSH() error {
-	if c.sshConn == nil {
-		return nil
-	}
-	if err := c.sshConn.Close(); err != nil {
-		return err
-	}
-	c.sshConn = nil
-	return nil
-}
-
-func address(cfg *config.Config) string {
-	port := cfg.Port
+func address(endpoint *config.Endpoint) string {
+	port := endpoint.Port
 	if port == 0 {
 		port = defaultSSHPort
 	}
-	return net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	return net.JoinHostPort(endpoint.Host, strconv.Itoa(port))
 }
@@ -0,0 +1,128 @@
+package sftpclient
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// handshakeGuard closes a connection whose SSH handshake takes longer than
+// the connect timeout. The clock is paused while the user is answering a
+// prompt, so typing an OTP never counts against it.
+type handshakeGuard struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    io.Closer
+	timer   *time.Timer
+	expired bool
+}
+
+func newHandshakeGuard(timeout time.Duration) *handshakeGuard {
+	return &handshakeGuard{timeout: timeout}
+}
+
+// arm starts the clock for conn.
+func (g *handshakeGuard) arm(conn io.Closer) {
+	if g.timeout <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.conn = conn
+	g.timer = time.AfterFunc(g.timeout, g.expire)
+}
+
+// disarm stops the clock and reports whether it had already run out.
+func (g *handshakeGuard) disarm() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.conn = nil
+	return g.expired
+}
+
+func (g *handshakeGuard) expire() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.conn == nil {
+		return
+	}
+	g.expired = true
+	g.conn.Close()
+}
+
+func (g *handshakeGuard) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil && !g.expired {
+		g.timer.Stop()
+	}
+}
+
+func (g *handshakeGuard) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timer != nil && !g.expired && g.conn != nil {
+		g.timer.Reset(g.timeout)
+	}
+}
+
+// wrap returns a Prompter that pauses the guard while waiting for the user.
+func (g *handshakeGuard) wrap(prompter Prompter) Prompter {
+	if prompter == nil {
+		return nil
+	}
+	return &pausingPrompter{Prompter: prompter, guard: g}
+}
+
+type pausingPrompter struct {
+	Prompter
+	guard *handshakeGuard
+}
+
+func (p *pausingPrompter) Confirm(title, message string) (bool, error) {
+	p.guard.pause()
+	defer p.guard.resume()
+	return p.Prompter.Confirm(title, message)
+}
+
+func (p *pausingPrompter) Challenge(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	p.guard.pause()
+	defer p.guard.resume()
+	return p.Prompter.Challenge(name, instruction, questions, echos)
+}
+
+// dialVia opens a direct-tcpip channel to addr through a jump host, giving
+// up after timeout.
+func dialVia(via *ssh.Client, addr string, timeout time.Duration) (net.Conn, error) {
+	if timeout <= 0 {
+		return via.Dial("tcp", addr)
+	}
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := via.Dial("tcp", addr)
+		done <- result{conn, err}
+	}()
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(timeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("dial %s: timed out after %s", addr, timeout)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package ui
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// accessTime returns the last access time recorded in info, falling back to
+// the modification time when the platform does not report one.
+func accessTime(info os.FileInfo) time.Time {
+	switch st := info.Sys().(type) {
+	case *sftp.FileStat:
+		return time.Unix(int64(st.Atime), 0)
+	case *syscall.Stat_t:
+		return time.Unix(st.Atim.Sec, st.Atim.Nsec)
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package ui
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+func accessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*sftp.FileStat); ok {
+		return time.Unix(int64(st.Atime), 0)
+	}
+	return info.ModTime()
+}
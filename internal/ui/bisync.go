@@ -0,0 +1,278 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var keepChoices = map[string]int{"s": keepNeither, "l": keepLocal, "r": keepRemote}
+
+var keepLabels = []string{"[skip]", "[keep local]", "[keep remote]"}
+
+// agree reports whether two entries count as in sync: both directories, or
+// files of equal size and modification time.
+func agree(a, b os.FileInfo) bool {
+	if !sameKind(a, b) {
+		return false
+	}
+	return a.IsDir() || (a.Size() == b.Size() && sameTime(a.ModTime(), b.ModTime()))
+}
+
+// sideChange describes how one side's entry differs from the last synced
+// record: "new", "modified", "deleted" or "unchanged".
+func sideChange(e treeEntry, exists bool, rec syncRecord, synced bool) string {
+	switch {
+	case !synced && exists:
+		return "new"
+	case !synced:
+		return "unchanged"
+	case !exists:
+		return "deleted"
+	case !rec.matches(e.info):
+		return "modified"
+	}
+	return "unchanged"
+}
+
+// planTwoWay compares localRoot and remoteRoot with each other and with the
+// state of the last run. A change on one side is copied to the other; paths
+// changed on both sides in different ways are conflicts, which are skipped
+// unless the user picks a side. Everything below a conflicting directory is
+// left alone.
+func planTwoWay(local, remote fileSystem, localRoot, remoteRoot string, state *syncState) (*syncPlan, error) {
+	localTree, _, err := scanTree(local, localRoot)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", localRoot, err)
+	}
+	remoteTree, _, err := scanTree(remote, remoteRoot)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", remoteRoot, err)
+	}
+	p := &syncPlan{
+		direction:   "local ↔ remote",
+		srcRoot:     localRoot,
+		dstRoot:     remoteRoot,
+		refreshPane: paneAll,
+		legs:        []*syncLeg{newSyncLeg(local, remote, remoteRoot), newSyncLeg(remote, local, localRoot)},
+		state:       state,
+	}
+
+	seen := map[string]bool{}
+	var paths []string
+	for _, tree := range []map[string]treeEntry{localTree, remoteTree} {
+		for rel := range tree {
+			if !seen[rel] {
+				seen[rel] = true
+				paths = append(paths, rel)
+			}
+		}
+	}
+	for rel := range state.Files {
+		if !seen[rel] {
+			seen[rel] = true
+			paths = append(paths, rel)
+		}
+	}
+	// A parent sorts before everything below it.
+	sort.Strings(paths)
+
+	blocked := map[string]bool{}
+	for _, rel := range paths {
+		if blocked[filepath.Dir(rel)] {
+			blocked[rel] = true
+			continue
+		}
+		l, lok := localTree[rel]
+		r, rok := remoteTree[rel]
+		rec, synced := state.Files[rel]
+		lChange := sideChange(l, lok, rec, synced)
+		rChange := sideChange(r, rok, rec, synced)
+		switch {
+		case lChange == "unchanged" && rChange == "unchanged":
+		case rChange == "unchanged":
+			p.propagate(rel, l, lok, r, rok, false)
+		case lChange == "unchanged":
+			p.propagate(rel, r, rok, l, lok, true)
+		case !lok && !rok:
+		case lok && rok && agree(l.info, r.info):
+		default:
+			a := syncAction{op: syncConflict, rel: rel, reason: fmt.Sprintf("local %s, remote %s", lChange, rChange)}
+			if lok {
+				a.local = &l
+				a.dir = l.info.IsDir()
+			}
+			if rok {
+				a.remote = &r
+				a.dir = a.dir || r.info.IsDir()
+			}
+			if a.dir {
+				blocked[rel] = true
+			}
+			p.actions = append(p.actions, a)
+		}
+	}
+	p.keepNonEmptyDirs(paths, localTree, remoteTree)
+	for _, a := range p.actions {
+		if a.op == syncDelete {
+			p.leg(a.toLocal).remove(a.rel)
+		}
+	}
+	if len(p.actions) == 0 {
+		// Nothing to copy, but what is in sync still has to be recorded, or
+		// a later delete on one side would be undone instead of propagated.
+		state.update(localTree, remoteTree)
+		if err := state.save(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// leg returns the leg that changes the local side, or the remote one.
+func (p *syncPlan) leg(toLocal bool) *syncLeg {
+	if toLocal {
+		return p.legs[1]
+	}
+	return p.legs[0]
+}
+
+// propagate makes the other side match from, which changed since the last
+// run while to did not. Deletes are only noted here and added to the leg once
+// the whole plan is known.
+func (p *syncPlan) propagate(rel string, from treeEntry, fromOK bool, to treeEntry, toOK, toLocal bool) {
+	switch {
+	case !fromOK:
+		if toOK {
+			p.actions = append(p.actions, syncAction{op: syncDelete, rel: rel, dir: to.info.IsDir(), toLocal: toLocal})
+		}
+		return
+	case toOK && sameKind(from.info, to.info) && from.info.IsDir():
+		return
+	}
+	op := syncCreate
+	if toOK {
+		op = syncUpdate
+		if !sameKind(from.info, to.info) {
+			p.actions = append(p.actions, syncAction{op: syncDelete, rel: rel, dir: to.info.IsDir(), toLocal: toLocal})
+			op = syncCreate
+		}
+	}
+	a := syncAction{op: op, rel: rel, dir: from.info.IsDir(), toLocal: toLocal}
+	if !a.dir {
+		a.size = from.info.Size()
+	}
+	p.actions = append(p.actions, a)
+	p.leg(toLocal).copy(rel, from)
+}
+
+// keepNonEmptyDirs drops directory deletes that would take something the plan
+// keeps with them, such as a file changed on the side where its directory was
+// not deleted. The directory then comes back on the other side with it. paths
+// holds every path of both trees, sorted.
+func (p *syncPlan) keepNonEmptyDirs(paths []string, localTree, remoteTree map[string]treeEntry) {
+	deleted := map[bool]map[string]bool{false: {}, true: {}}
+	for _, a := range p.actions {
+		if a.op == syncDelete {
+			deleted[a.toLocal][a.rel] = true
+		}
+	}
+	kept := p.actions[:0]
+	for _, a := range p.actions {
+		if a.op == syncDelete && a.dir {
+			tree := remoteTree
+			if a.toLocal {
+				tree = localTree
+			}
+			if keepsChildren(paths, tree, deleted[a.toLocal], a.rel) {
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+	p.actions = kept
+}
+
+// keepsChildren reports whether anything below dir in tree stays. Everything
+// below dir shares its prefix, so it is one run of the sorted paths.
+func keepsChildren(paths []string, tree map[string]treeEntry, deleted map[string]bool, dir string) bool {
+	prefix := dir + string(filepath.Separator)
+	for i := sort.SearchStrings(paths, prefix); i < len(paths) && strings.HasPrefix(paths[i], prefix); i++ {
+		if _, ok := tree[paths[i]]; ok && !deleted[paths[i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// choose sets which side wins the conflict at i. Conflicts involving a
+// directory can only be skipped and are left to be resolved by hand.
+func (p *syncPlan) choose(i, choice int) {
+	if i < 0 || i >= len(p.actions) {
+		return
+	}
+	a := &p.actions[i]
+	if a.op != syncConflict || a.dir {
+		return
+	}
+	a.choice = choice
+}
+
+// resolveConflicts adds the side picked for each conflict to the legs.
+func (p *syncPlan) resolveConflicts() {
+	for _, a := range p.actions {
+		if a.op != syncConflict || a.choice == keepNeither {
+			continue
+		}
+		from, toLocal := a.local, false
+		if a.choice == keepRemote {
+			from, toLocal = a.remote, true
+		}
+		leg := p.leg(toLocal)
+		if from != nil {
+			leg.copy(a.rel, *from)
+		} else {
+			leg.remove(a.rel)
+		}
+	}
+}
+
+// saveState rescans both sides after a two-way run and stores what they now
+// agree on.
+func (p *syncPlan) saveState() error {
+	push := p.legs[0]
+	localTree, _, err := scanTree(push.src, p.srcRoot)
+	if err != nil {
+		return fmt.Errorf("scan %s: %w", p.srcRoot, err)
+	}
+	remoteTree, _, err := scanTree(push.dst, p.dstRoot)
+	if err != nil {
+		return fmt.Errorf("scan %s: %w", p.dstRoot, err)
+	}
+	p.state.update(localTree, remoteTree)
+	return p.state.save()
+}
+
+// startTwoWaySync compares the local and remote working directories with
+// each other and with the last run in the background.
+func (m *model) startTwoWaySync() tea.Cmd {
+	if len(m.panes) < 2 || m.remote() == nil {
+		return tea.Printf("remote client unavailable")
+	}
+	localRoot, remoteRoot := m.panes[paneLocal].cwd, m.panes[paneRemote].cwd
+	local, remote := localFS{}, remoteFS{client: m.client}
+	stateDir := m.transferCfg.syncStateDir
+	plan := func() tea.Msg {
+		state, err := loadSyncState(stateDir, localRoot, remoteRoot)
+		if err != nil {
+			return syncPlanMsg{err: err}
+		}
+		p, err := planTwoWay(local, remote, localRoot, remoteRoot, state)
+		return syncPlanMsg{plan: p, err: err}
+	}
+	return tea.Batch(tea.Printf("comparing %s with %s", localRoot, remoteRoot), plan)
+}
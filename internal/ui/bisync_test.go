@@ -0,0 +1,204 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// syncedPair creates the same files on both sides and records them as
+// synced, as after a successful two-way run.
+func syncedPair(t *testing.T, files map[string]string) (string, string, *syncState) {
+	t.Helper()
+	dir := t.TempDir()
+	local, remote := filepath.Join(dir, "local"), filepath.Join(dir, "remote")
+	writeTree(t, local, files)
+	writeTree(t, remote, files)
+	state, err := loadSyncState(filepath.Join(dir, "state"), local, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localTree, _, err := scanTree(localFS{}, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteTree, _, err := scanTree(localFS{}, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.update(localTree, remoteTree)
+	return local, remote, state
+}
+
+// edit rewrites the file at path as a later change would.
+func edit(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, path, testMtime.Add(time.Hour))
+}
+
+func removeAll(t *testing.T, path string) {
+	t.Helper()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanTwoWay(t *testing.T) {
+	tests := []struct {
+		name   string
+		synced map[string]string
+		change func(t *testing.T, local, remote string)
+		want   []string
+	}{
+		{
+			name: "first run copies both ways",
+			change: func(t *testing.T, local, remote string) {
+				edit(t, filepath.Join(local, "a"), "local")
+				edit(t, filepath.Join(remote, "b"), "remote")
+			},
+			want: []string{"→ + a", "← + b"},
+		},
+		{
+			name:   "unchanged",
+			synced: map[string]string{"f": "v1", "d/": "", "d/g": "g"},
+			change: func(*testing.T, string, string) {},
+		},
+		{
+			name:   "one-sided edits propagate",
+			synced: map[string]string{"f": "v1", "g": "v1"},
+			change: func(t *testing.T, local, remote string) {
+				edit(t, filepath.Join(local, "f"), "local v2")
+				edit(t, filepath.Join(remote, "g"), "remote v2")
+			},
+			want: []string{"→ ~ f", "← ~ g"},
+		},
+		{
+			name:   "deletes propagate",
+			synced: map[string]string{"f": "v1", "g": "v1"},
+			change: func(t *testing.T, local, remote string) {
+				removeAll(t, filepath.Join(local, "f"))
+				removeAll(t, filepath.Join(remote, "g"))
+			},
+			want: []string{"→ - f", "← - g"},
+		},
+		{
+			name:   "edited on both sides",
+			synced: map[string]string{"f": "v1"},
+			change: func(t *testing.T, local, remote string) {
+				edit(t, filepath.Join(local, "f"), "local v2")
+				edit(t, filepath.Join(remote, "f"), "remote v2!")
+			},
+			want: []string{"! f"},
+		},
+		{
+			name:   "same edit on both sides",
+			synced: map[string]string{"f": "v1"},
+			change: func(t *testing.T, local, remote string) {
+				edit(t, filepath.Join(local, "f"), "v2")
+				edit(t, filepath.Join(remote, "f"), "v2")
+			},
+		},
+		{
+			name:   "edited on one side, deleted on the other",
+			synced: map[string]string{"f": "v1"},
+			change: func(t *testing.T, local, remote string) {
+				edit(t, filepath.Join(local, "f"), "local v2")
+				removeAll(t, filepath.Join(remote, "f"))
+			},
+			want: []string{"! f"},
+		},
+		{
+			name:   "directory with a new child is kept",
+			synced: map[string]string{"d/": "", "d/old": "o"},
+			change: func(t *testing.T, local, remote string) {
+				removeAll(t, filepath.Join(remote, "d"))
+				edit(t, filepath.Join(local, "d", "new"), "n")
+			},
+			want: []string{"→ + d/new", "← - d/old"},
+		},
+		{
+			name:   "emptied directory is deleted",
+			synced: map[string]string{"d/": "", "d/old": "o"},
+			change: func(t *testing.T, local, remote string) {
+				removeAll(t, filepath.Join(remote, "d"))
+			},
+			want: []string{"← - d", "← - d/old"},
+		},
+		{
+			name:   "conflicting directory blocks its children",
+			synced: map[string]string{"d": "file"},
+			change: func(t *testing.T, local, remote string) {
+				removeAll(t, filepath.Join(local, "d"))
+				edit(t, filepath.Join(local, "d", "x"), "x")
+				edit(t, filepath.Join(remote, "d"), "remote v2")
+			},
+			want: []string{"! d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, remote, state := syncedPair(t, tt.synced)
+			tt.change(t, local, remote)
+
+			p, err := planTwoWay(localFS{}, localFS{}, local, remote, state)
+			if err != nil {
+				t.Fatalf("planTwoWay: %v", err)
+			}
+			if got := actionList(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTwoWayConflictChoice(t *testing.T) {
+	local, remote, state := syncedPair(t, map[string]string{"f": "v1"})
+	edit(t, filepath.Join(local, "f"), "local v2")
+	edit(t, filepath.Join(remote, "f"), "remote v2!")
+	p, err := planTwoWay(localFS{}, localFS{}, local, remote, state)
+	if err != nil {
+		t.Fatalf("planTwoWay: %v", err)
+	}
+
+	p.choose(0, keepRemote)
+	p.resolveConflicts()
+	if push := p.leg(false); len(push.items) != 0 {
+		t.Fatalf("keeping the remote side uploads %d files", len(push.items))
+	}
+	pull := p.leg(true)
+	if len(pull.items) != 1 || pull.items[0].src != filepath.Join(remote, "f") || pull.items[0].dst != filepath.Join(local, "f") {
+		t.Fatalf("download items = %+v, want remote f over local f", pull.items)
+	}
+}
+
+func TestKeepsChildren(t *testing.T) {
+	paths := []string{"a", "a-b", "a/x", "a/y", "a/y/z", "ab"}
+	tree := map[string]treeEntry{"a": {}, "a-b": {}, "a/x": {}, "a/y": {}, "a/y/z": {}, "ab": {}}
+	tests := []struct {
+		name    string
+		dir     string
+		deleted map[string]bool
+		want    bool
+	}{
+		{name: "children stay", dir: "a", deleted: map[string]bool{"a": true}, want: true},
+		{name: "siblings with a common prefix are not children", dir: "a", deleted: map[string]bool{"a/x": true, "a/y": true, "a/y/z": true}},
+		{name: "only a nested child stays", dir: "a", deleted: map[string]bool{"a/x": true, "a/y": true}, want: true},
+		{name: "nested directory emptied", dir: "a/y", deleted: map[string]bool{"a/y/z": true}},
+		{name: "no children", dir: "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keepsChildren(paths, tree, tt.deleted, tt.dir); got != tt.want {
+				t.Fatalf("keepsChildren(%q) = %v, want %v", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
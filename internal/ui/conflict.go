@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+var conflictOptions = []struct {
+	policy string
+	option promptOption
+}{
+	{config.ConflictOverwrite, promptOption{key: "o", label: "overwrite"}},
+	{config.ConflictSkip, promptOption{key: "s", label: "skip"}},
+	{config.ConflictRename, promptOption{key: "r", label: "keep both, rename the new copy"}},
+	{config.ConflictNewer, promptOption{key: "n", label: "overwrite if the source is newer"}},
+	{config.ConflictSize, promptOption{key: "d", label: "overwrite if the sizes differ"}},
+}
+
+// resolveConflict decides where item goes when its destination exists. It
+// returns the destination path to write, or skip when the file should be left
+// alone. Overwriting keeps resume in play, so a partial copy is continued
+// rather than replaced.
+func (t *transferTask) resolveConflict(item transferItem) (string, bool, error) {
+	existing, err := t.dst.Stat(item.dst)
+	if err != nil {
+		return item.dst, false, nil
+	}
+	if existing.IsDir() {
+		return "", false, fmt.Errorf("%s exists and is a directory", item.dst)
+	}
+	policy := t.cfg.conflict
+	if t.conflictAll != "" {
+		policy = t.conflictAll
+	}
+	if policy == config.ConflictAsk {
+		var all bool
+		policy, all, err = t.askConflict(item, existing)
+		if err != nil {
+			return "", false, err
+		}
+		if all {
+			t.conflictAll = policy
+		}
+	}
+	switch policy {
+	case config.ConflictSkip:
+		return "", true, nil
+	case config.ConflictRename:
+		dst, err := t.freeName(item.dst)
+		return dst, false, err
+	case config.ConflictNewer:
+		return item.dst, !item.modTime.After(existing.ModTime()), nil
+	case config.ConflictSize:
+		return item.dst, item.size == existing.Size(), nil
+	default:
+		return item.dst, false, nil
+	}
+}
+
+func (t *transferTask) askConflict(item transferItem, existing os.FileInfo) (string, bool, error) {
+	options := make([]promptOption, len(conflictOptions))
+	for i, c := range conflictOptions {
+		options[i] = c.option
+	}
+	message := fmt.Sprintf(
+		"%s already exists.\n\nSource       %s  %s\nDestination  %s  %s",
+		item.dst,
+		humanSize(item.size), item.modTime.Format(time.DateTime),
+		humanSize(existing.Size()), existing.ModTime().Format(time.DateTime),
+	)
+	choice, all, err := t.cfg.prompter.choose("File exists", message, options, t.files.Load() > 1)
+	if errors.Is(err, errCanceled) {
+		t.ctl.cancel()
+		return "", false, t.ctl.ctx.Err()
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("%s exists: %w", item.dst, err)
+	}
+	return conflictOptions[choice].policy, all, nil
+}
+
+// freeName finds the first "name (n).ext" next to path that does not exist.
+// A leading dot starts a hidden name rather than an extension, so ".bashrc"
+// becomes ".bashrc (1)".
+func (t *transferTask) freeName(path string) (string, error) {
+	ext := filepath.Ext(path)
+	if ext == filepath.Base(path) {
+		ext = ""
+	}
+	base := strings.TrimSuffix(path, ext)
+	for n := 1; n < 1000; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := t.dst.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free name for %s", path)
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+func TestFreeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string
+		path     string
+		want     string
+	}{
+		{name: "extension", path: "report.txt", want: "report (1).txt"},
+		{name: "taken names skipped", existing: map[string]string{"report (1).txt": "", "report (2).txt": ""}, path: "report.txt", want: "report (3).txt"},
+		{name: "only the last extension", path: "archive.tar.gz", want: "archive.tar (1).gz"},
+		{name: "no extension", path: "Makefile", want: "Makefile (1)"},
+		{name: "dotfile", path: ".bashrc", want: ".bashrc (1)"},
+		{name: "dotfile with extension", path: ".config.yaml", want: ".config (1).yaml"},
+		{name: "dot in the directory", path: "v1.2/notes", want: "v1.2/notes (1)"},
+		{name: "directory in the way", existing: map[string]string{"data (1)/": ""}, path: "data", want: "data (2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.existing)
+			task := newTransferTask("Upload", localFS{}, localFS{}, "src", dir, paneLocal, transferConfig{})
+
+			got, err := task.freeName(filepath.Join(dir, tt.path))
+			if err != nil {
+				t.Fatalf("freeName: %v", err)
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Fatalf("freeName(%q) = %q, want %q", tt.path, got, want)
+			}
+		})
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	tests := []struct {
+		policy  string
+		size    int64
+		modTime time.Time
+		wantDst string
+		skip    bool
+	}{
+		{policy: config.ConflictOverwrite, wantDst: "f"},
+		{policy: config.ConflictSkip, skip: true},
+		{policy: config.ConflictRename, wantDst: "f (1)"},
+		{policy: config.ConflictNewer, modTime: testMtime.Add(time.Second), wantDst: "f"},
+		{policy: config.ConflictNewer, modTime: testMtime, skip: true},
+		{policy: config.ConflictSize, size: 5, wantDst: "f"},
+		{policy: config.ConflictSize, size: 8, skip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, map[string]string{"f": "existing"})
+			task := newTransferTask("Upload", localFS{}, localFS{}, "src", dir, paneLocal, transferConfig{conflict: tt.policy})
+			item := transferItem{src: "src", dst: filepath.Join(dir, "f"), size: tt.size, modTime: tt.modTime}
+
+			dst, skip, err := task.resolveConflict(item)
+			if err != nil {
+				t.Fatalf("resolveConflict: %v", err)
+			}
+			if skip != tt.skip {
+				t.Fatalf("skip = %v, want %v", skip, tt.skip)
+			}
+			if !tt.skip && dst != filepath.Join(dir, tt.wantDst) {
+				t.Fatalf("dst = %q, want %q", dst, filepath.Join(dir, tt.wantDst))
+			}
+		})
+	}
+}
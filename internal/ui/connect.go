@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type connectResultMsg struct {
+	err error
+}
+
+// Connect shows a connecting screen while dial runs, so prompts raised during
+// the handshake can be answered before the file panes open.
+func Connect(target string, prompter *Prompter, dial func() error) error {
+	m := newConnectModel(target, dial)
+	program := tea.NewProgram(m, tea.WithAltScreen())
+	if prompter != nil {
+		prompter.Attach(program)
+		defer prompter.Detach()
+	}
+	final, err := program.Run()
+	if err != nil {
+		return err
+	}
+	return final.(*connectModel).result()
+}
+
+type connectModel struct {
+	target   string
+	dial     func() error
+	spinner  spinner.Model
+	dialog   *promptDialog
+	width    int
+	finished bool
+	err      error
+}
+
+func newConnectModel(target string, dial func() error) *connectModel {
+	return &connectModel{
+		target:  target,
+		dial:    dial,
+		spinner: spinner.New(spinner.WithSpinner(spinner.Dot)),
+	}
+}
+
+func (m *connectModel) Init() tea.Cmd {
+	dial := m.dial
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return connectResultMsg{err: dial()}
+	})
+}
+
+func (m *connectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			if m.dialog != nil {
+				m.dialog.cancel(errCanceled)
+				m.dialog = nil
+			}
+			return m, tea.Quit
+		}
+		if m.dialog != nil && m.dialog.handleKey(msg) {
+			m.dialog = nil
+		}
+	case promptMsg:
+		if m.dialog != nil {
+			m.dialog.cancel(errPromptClosed)
+		}
+		m.dialog = newPromptDialog(msg.req)
+	case connectResultMsg:
+		m.finished = true
+		m.err = msg.err
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m *connectModel) View() string {
+	status := fmt.Sprintf("%s Connecting to %s…", m.spinner.View(), m.target)
+	if m.dialog == nil {
+		return lipgloss.JoinVertical(lipgloss.Left, headerStyle.Render("termftp"), status)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, headerStyle.Render("termftp"), status, "", m.dialog.view(m.width))
+}
+
+func (m *connectModel) result() error {
+	if !m.finished {
+		return errors.New("connection canceled")
+	}
+	return m.err
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/m1kkY8/termftp/internal/sftpclient"
+)
+
+var (
+	connectedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	reconnectingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+)
+
+type connStatusMsg struct {
+	status sftpclient.Status
+}
+
+// waitForConnection delivers the next connection state change as a message.
+func (m *model) waitForConnection() tea.Cmd {
+	if m.client == nil {
+		return nil
+	}
+	events := m.client.Events()
+	return func() tea.Msg {
+		return connStatusMsg{status: <-events}
+	}
+}
+
+func (m *model) handleConnStatus(status sftpclient.Status) tea.Cmd {
+	if len(m.panes) <= paneRemote {
+		return nil
+	}
+	remote := m.panes[paneRemote]
+	remote.status = formatConnStatus(status)
+	switch status.State {
+	case sftpclient.StateConnected:
+		// Rebind the pane to the new session at the same directory.
+		_ = remote.changeDirectory(remote.cwd)
+		return tea.Batch(tea.Printf("reconnected"), m.waitForConnection())
+	case sftpclient.StateClosed:
+		return nil
+	}
+	return m.waitForConnection()
+}
+
+func formatConnStatus(status sftpclient.Status) string {
+	switch status.State {
+	case sftpclient.StateConnected:
+		return connectedStyle.Render("● connected")
+	case sftpclient.StateReconnecting:
+		label := "reconnecting"
+		if status.Attempt > 0 {
+			label = fmt.Sprintf("reconnecting (attempt %d)", status.Attempt)
+		}
+		if status.Err != nil {
+			label += ": " + status.Err.Error()
+		}
+		return reconnectingStyle.Render("● " + label)
+	default:
+		return errorStyle.Render("● disconnected")
+	}
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// transferControl lets the UI cancel, pause or throttle a task while its copy
+// goroutines run. Copies call wait between chunks, which blocks while paused
+// and fails once the task is canceled, and throttle before every write.
+type transferControl struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	limiter *rateLimiter
+	global  *rateLimiter
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+func newTransferControl(cfg transferConfig) *transferControl {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &transferControl{
+		ctx:     ctx,
+		cancel:  cancel,
+		limiter: newRateLimiter(cfg.transferLimit),
+		global:  cfg.limiter,
+	}
+}
+
+func (c *transferControl) wait() error {
+	c.mu.Lock()
+	resume := c.resume
+	c.mu.Unlock()
+	if resume != nil {
+		select {
+		case <-resume:
+		case <-c.ctx.Done():
+		}
+	}
+	return c.ctx.Err()
+}
+
+// sleep waits for d unless the task is canceled first.
+func (c *transferControl) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+func (c *transferControl) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		return
+	}
+	c.paused = true
+	c.resume = make(chan struct{})
+}
+
+func (c *transferControl) unpause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	close(c.resume)
+	c.resume = nil
+}
+
+func (c *transferControl) togglePause() {
+	if c.isPaused() {
+		c.unpause()
+	} else {
+		c.pause()
+	}
+}
+
+func (c *transferControl) isPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *transferControl) canceled() bool {
+	return c.ctx.Err() != nil
+}
+
+// limited reports whether any limit currently applies to the task.
+func (c *transferControl) limited() bool {
+	return c.limiter.limit() > 0 || (c.global != nil && c.global.limit() > 0)
+}
+
+// throttle waits until n bytes may be written under both the task's own limit
+// and the global one.
+func (c *transferControl) throttle(n int) error {
+	if err := c.limiter.wait(c.ctx, n); err != nil {
+		return err
+	}
+	if c.global != nil {
+		return c.global.wait(c.ctx, n)
+	}
+	return nil
+}
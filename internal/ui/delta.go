@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"github.com/m1kkY8/termftp/internal/sftpclient"
+)
+
+// deltaBlockSize is the unit a delta copy compares and rewrites.
+const deltaBlockSize = 4 * 1024 * 1024
+
+// dataCopier is implemented by file systems whose server can copy data
+// between two of its files without it crossing the connection.
+type dataCopier interface {
+	CanCopyData() bool
+	CopyData(src, dst string, ranges []sftpclient.Range) error
+}
+
+// useDelta reports whether item should be sent as a delta against its
+// existing destination. An interrupted atomic upload is left to resume
+// instead. Atomic uploads also need the server to copy the unchanged blocks
+// into the temporary file; without that they are sent whole.
+func (t *transferTask) useDelta(item transferItem) bool {
+	if !t.cfg.delta {
+		return false
+	}
+	info, err := t.dst.Stat(item.dst)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return false
+	}
+	if t.atomic {
+		copier, ok := t.dst.(dataCopier)
+		if !ok || !copier.CanCopyData() {
+			return false
+		}
+		if _, err := t.dst.Stat(partPath(item.dst)); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// openDelta compares item's source with base, the existing destination,
+// block by block and opens item's destination for writing. When that is base
+// itself it is updated in place; otherwise it is created and the blocks that
+// did not change are copied into it from base on the server. It returns the
+// offsets of the blocks that differ; only those need sending.
+func (t *transferTask) openDelta(item transferItem, base string) (transferFile, []int64, error) {
+	t.setCurrent(nil, "comparing "+filepath.Base(item.src))
+	var srcSums [][]byte
+	var srcErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srcSums, srcErr = t.src.BlockChecksums(item.src, deltaBlockSize)
+	}()
+	dstSums, err := t.dst.BlockChecksums(base, deltaBlockSize)
+	<-done
+	if srcErr != nil {
+		return nil, nil, fmt.Errorf("checksum source blocks: %w", srcErr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("checksum destination blocks: %w", err)
+	}
+
+	blocks := []int64{}
+	var same []sftpclient.Range
+	for i, sum := range srcSums {
+		off := int64(i) * deltaBlockSize
+		if i < len(dstSums) && bytes.Equal(sum, dstSums[i]) {
+			same = appendRange(same, off, minInt64(deltaBlockSize, item.size-off))
+			continue
+		}
+		blocks = append(blocks, off)
+	}
+
+	if base != item.dst {
+		f, err := t.create(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(same) > 0 {
+			t.setCurrent(nil, "copying unchanged blocks of "+filepath.Base(item.src))
+			if err := t.dst.(dataCopier).CopyData(base, item.dst, same); err != nil {
+				// Fall back to sending every block into an empty file, so
+				// nothing a partial copy left behind survives.
+				if err := f.Truncate(0); err != nil {
+					f.Close()
+					return nil, nil, err
+				}
+				blocks = blocks[:0]
+				for off := int64(0); off < item.size; off += deltaBlockSize {
+					blocks = append(blocks, off)
+				}
+			}
+		}
+		return f, blocks, nil
+	}
+	f, err := t.dst.OpenWrite(item.dst)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := f.Truncate(item.size); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, blocks, nil
+}
+
+// appendRange adds [off, off+length) to ranges, extending the last range
+// when the two touch.
+func appendRange(ranges []sftpclient.Range, off, length int64) []sftpclient.Range {
+	if n := len(ranges); n > 0 && ranges[n-1].Offset+ranges[n-1].Length == off {
+		ranges[n-1].Length += length
+		return ranges
+	}
+	return append(ranges, sftpclient.Range{Offset: off, Length: length})
+}
+
+// changedBytes is how much of a size-byte file the given blocks cover.
+func changedBytes(blocks []int64, size int64) int64 {
+	var n int64
+	for _, off := range blocks {
+		n += minInt64(deltaBlockSize, size-off)
+	}
+	return n
+}
@@ -0,0 +1,315 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/m1kkY8/termftp/internal/sftpclient"
+)
+
+// transferFile is the subset of *os.File and *sftp.File the transfer engine
+// relies on.
+type transferFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Closer
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+}
+
+// fileSystem is one side of a transfer: the local disk or the remote server.
+type fileSystem interface {
+	Open(path string) (transferFile, error)
+	// Create opens path for writing, truncating it.
+	Create(path string) (transferFile, error)
+	// OpenWrite opens an existing file for reading and writing without
+	// truncating it, so a partial copy can be continued.
+	OpenWrite(path string) (transferFile, error)
+	Stat(path string) (os.FileInfo, error)
+	MkdirAll(path string) error
+	Remove(path string) error
+	// Rename moves oldpath to newpath, replacing newpath if it exists.
+	Rename(oldpath, newpath string) error
+	Chmod(path string, mode os.FileMode) error
+	Chtimes(path string, atime, mtime time.Time) error
+	// Checksum returns the SHA-256 digest of the file at path. When the
+	// file has to be read over the network, throttle, unless nil, is called
+	// with the size of every chunk first.
+	Checksum(path string, throttle func(n int) error) ([]byte, error)
+	// BlockChecksums returns the SHA-256 of each blockSize block of path.
+	BlockChecksums(path string, blockSize int64) ([][]byte, error)
+	// Walk visits root and everything below it, parents before children.
+	Walk(root string, fn func(path string, info os.FileInfo) error) error
+}
+
+type localFS struct{}
+
+func (localFS) Open(path string) (transferFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	adviseSequential(f)
+	return f, nil
+}
+
+func (localFS) Create(path string) (transferFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	adviseSequential(f)
+	return f, nil
+}
+
+func (localFS) OpenWrite(path string) (transferFile, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+func (localFS) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (localFS) MkdirAll(path string) error {
+	return os.MkdirAll(path, 0o755)
+}
+
+func (localFS) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (localFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (localFS) Chmod(path string, mode os.FileMode) error {
+	return os.Chmod(path, mode)
+}
+
+func (localFS) Chtimes(path string, atime, mtime time.Time) error {
+	return os.Chtimes(path, atime, mtime)
+}
+
+func (localFS) Checksum(path string, _ func(n int) error) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	adviseSequential(f)
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func (localFS) BlockChecksums(path string, blockSize int64) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	adviseSequential(f)
+	var sums [][]byte
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			sum := sha256.Sum256(buf[:n])
+			sums = append(sums, sum[:])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (localFS) Walk(root string, fn func(path string, info os.FileInfo) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(path, info)
+	})
+}
+
+// remoteFS resolves the live SFTP session on every call so that work started
+// after a reconnect uses the new connection.
+type remoteFS struct {
+	client *sftpclient.Client
+}
+
+func (r remoteFS) sftp() (*sftp.Client, error) {
+	if r.client == nil {
+		return nil, sftpclient.ErrNotConnected
+	}
+	if c := r.client.SFTP(); c != nil {
+		return c, nil
+	}
+	return nil, sftpclient.ErrNotConnected
+}
+
+func (r remoteFS) Open(path string) (transferFile, error) {
+	c, err := r.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(path)
+}
+
+func (r remoteFS) Create(path string) (transferFile, error) {
+	c, err := r.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+}
+
+func (r remoteFS) OpenWrite(path string) (transferFile, error) {
+	c, err := r.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenFile(path, os.O_RDWR)
+}
+
+func (r remoteFS) Stat(path string) (os.FileInfo, error) {
+	c, err := r.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return c.Stat(path)
+}
+
+func (r remoteFS) MkdirAll(path string) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	return ensureRemoteDir(c, path)
+}
+
+func (r remoteFS) Remove(path string) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	return c.Remove(path)
+}
+
+// Rename uses posix-rename@openssh.com where available, which replaces the
+// target atomically. Plain SFTP rename refuses to overwrite, so without the
+// extension an existing target is first moved aside and only deleted once
+// oldpath has taken its place; if that fails the target is put back.
+func (r remoteFS) Rename(oldpath, newpath string) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	if _, ok := c.HasExtension("posix-rename@openssh.com"); ok {
+		return c.PosixRename(oldpath, newpath)
+	}
+	err = c.Rename(oldpath, newpath)
+	if err == nil {
+		return nil
+	}
+	// Only a target in the way is worth working around; anything else,
+	// such as a missing source or a denied write, is reported as is.
+	if _, serr := c.Lstat(oldpath); serr != nil {
+		return err
+	}
+	if _, serr := c.Lstat(newpath); serr != nil {
+		return err
+	}
+	aside := asidePath(newpath)
+	if aerr := c.Rename(newpath, aside); aerr != nil {
+		return err
+	}
+	if err := c.Rename(oldpath, newpath); err != nil {
+		if rerr := c.Rename(aside, newpath); rerr != nil {
+			return fmt.Errorf("%w; previous file kept as %s", err, aside)
+		}
+		return err
+	}
+	_ = c.Remove(aside)
+	return nil
+}
+
+// asideSuffix marks the previous version of a file while a plain SFTP rename
+// replaces it.
+const asideSuffix = ".termftp-old"
+
+func asidePath(path string) string {
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, "."+name+asideSuffix)
+}
+
+func (r remoteFS) Chmod(path string, mode os.FileMode) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	return c.Chmod(path, mode)
+}
+
+func (r remoteFS) Chtimes(path string, atime, mtime time.Time) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	return c.Chtimes(path, atime, mtime)
+}
+
+func (r remoteFS) Checksum(path string, throttle func(n int) error) ([]byte, error) {
+	if r.client == nil {
+		return nil, sftpclient.ErrNotConnected
+	}
+	return r.client.Checksum(path, throttle)
+}
+
+func (r remoteFS) BlockChecksums(path string, blockSize int64) ([][]byte, error) {
+	if r.client == nil {
+		return nil, sftpclient.ErrNotConnected
+	}
+	return r.client.BlockChecksums(path, blockSize)
+}
+
+func (r remoteFS) CanCopyData() bool {
+	return r.client != nil && r.client.CanCopyData()
+}
+
+func (r remoteFS) CopyData(src, dst string, ranges []sftpclient.Range) error {
+	if r.client == nil {
+		return sftpclient.ErrNotConnected
+	}
+	return r.client.CopyData(src, dst, ranges)
+}
+
+func (r remoteFS) Walk(root string, fn func(path string, info os.FileInfo) error) error {
+	c, err := r.sftp()
+	if err != nil {
+		return err
+	}
+	walker := c.Walk(root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		if err := fn(walker.Path(), walker.Stat()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
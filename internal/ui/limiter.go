@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limitChunk caps how much is written at once while a limit is active, so
+// throttled streams send steadily instead of in buffer-sized bursts.
+const limitChunk = 256 * 1024
+
+// bandwidthSteps are the limits + and - cycle through; 0 means unlimited.
+var bandwidthSteps = []int64{
+	256 * 1024,
+	512 * 1024,
+	1024 * 1024,
+	2 * 1024 * 1024,
+	5 * 1024 * 1024,
+	10 * 1024 * 1024,
+	20 * 1024 * 1024,
+	50 * 1024 * 1024,
+	100 * 1024 * 1024,
+}
+
+// rateLimiter is a token bucket shared by every stream it throttles. Writers
+// may overdraw it and then sleep off the debt, which keeps the average rate
+// exact without splitting writes further than limitChunk.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    int64
+	tokens  float64
+	last    time.Time
+	changed chan struct{}
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{rate: maxInt64(rate, 0), last: time.Now(), changed: make(chan struct{})}
+}
+
+func (l *rateLimiter) limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// setLimit changes the rate in bytes per second, 0 for unlimited. Writers
+// sleeping on the old rate are released.
+func (l *rateLimiter) setLimit(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = maxInt64(rate, 0)
+	l.tokens = 0
+	l.last = time.Now()
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// step moves the limit to the next preset: up towards unlimited when dir is
+// positive, down otherwise.
+func (l *rateLimiter) step(dir int) int64 {
+	rate := nextBandwidthStep(l.limit(), dir)
+	l.setLimit(rate)
+	return rate
+}
+
+func nextBandwidthStep(rate int64, dir int) int64 {
+	if dir > 0 {
+		if rate <= 0 {
+			return 0
+		}
+		for _, step := range bandwidthSteps {
+			if step > rate {
+				return step
+			}
+		}
+		return 0
+	}
+	if rate <= 0 {
+		return bandwidthSteps[len(bandwidthSteps)-1]
+	}
+	for i := len(bandwidthSteps) - 1; i >= 0; i-- {
+		if bandwidthSteps[i] < rate {
+			return bandwidthSteps[i]
+		}
+	}
+	return bandwidthSteps[0]
+}
+
+// wait takes n bytes from the bucket, sleeping while it is in debt.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	rate := float64(l.rate)
+	l.tokens += now.Sub(l.last).Seconds() * rate
+	if burst := rate / 4; l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / rate * float64(time.Second))
+	changed := l.changed
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-changed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -5,10 +5,12 @@ import (
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pkg/sftp"
 )
 
 func New(opts Options) *model {
 	transferCfg := normalizeTransferOptions(opts.Transfer)
+	transferCfg.prompter = opts.Prompter
 	local := newPane("Local", defaultLocalRoot(opts.LocalRoot), localProvider{}, false)
 	remoteProvider := dirProvider(localProvider{})
 	readonly := true
@@ -17,6 +19,9 @@ func New(opts Options) *model {
 		readonly = false
 	}
 	remote := newPane("Remote", defaultRemoteRoot(opts.RemoteRoot), remoteProvider, readonly)
+	if opts.Client != nil {
+		remote.status = formatConnStatus(opts.Client.Status())
+	}
 
 	local.focus(true)
 	remote.focus(false)
@@ -33,7 +38,15 @@ func New(opts Options) *model {
 	}
 }
 
-func (m *model) Init() tea.Cmd { return nil }
+func (m *model) Init() tea.Cmd { return m.waitForConnection() }
+
+// remote returns the live SFTP session, or nil while disconnected.
+func (m *model) remote() *sftp.Client {
+	if m.client == nil {
+		return nil
+	}
+	return m.client.SFTP()
+}
 
 func (m *model) activePane() *pane {
 	if len(m.panes) == 0 {
@@ -109,9 +122,33 @@ func normalizeTransferOptions(opts TransferOptions) transferConfig {
 	if interval > time.Second {
 		interval = time.Second
 	}
+	concurrent := opts.ConcurrentTransfers
+	if concurrent <= 0 {
+		concurrent = 2
+	}
+	if concurrent > 8 {
+		concurrent = 8
+	}
 	return transferConfig{
 		bufferSize:       bufferSize,
 		streams:          streams,
 		progressInterval: interval,
+		concurrent:       concurrent,
+		keepPartial:      opts.KeepPartial,
+		resume:           opts.Resume,
+		verifyResume:     opts.VerifyResume,
+		verify:           opts.VerifyChecksums,
+		limiter:          newRateLimiter(opts.MaxBandwidth),
+		transferLimit:    opts.TransferBandwidth,
+		attempts:         max(opts.MaxAttempts, 1),
+		conflict:         opts.Conflict,
+		preserveTimes:    opts.PreserveTimes,
+		preserveMode:     opts.PreserveMode,
+		delta:            opts.Delta,
+		syncChecksum:     opts.SyncChecksums,
+		syncDelete:       opts.SyncDeletes,
+		syncStateDir:     opts.SyncStateDir,
+		watchDebounce:    opts.WatchDebounce,
+		watchDelete:      opts.WatchDeletes,
 	}
 }
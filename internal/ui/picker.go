@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var selectedChoiceStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("229")).
+	Background(focusedBorder)
+
+// Choice is one entry of a startup picker.
+type Choice struct {
+	Name   string
+	Detail string
+}
+
+// PickProfile lets the user choose a connection profile before connecting.
+func PickProfile(choices []Choice) (string, error) {
+	if len(choices) == 0 {
+		return "", errors.New("no profiles to choose from")
+	}
+	final, err := tea.NewProgram(&pickerModel{title: "Select a profile", choices: choices}, tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+	m := final.(*pickerModel)
+	if !m.chosen {
+		return "", errCanceled
+	}
+	return m.choices[m.cursor].Name, nil
+}
+
+type pickerModel struct {
+	title   string
+	choices []Choice
+	cursor  int
+	chosen  bool
+}
+
+func (m *pickerModel) Init() tea.Cmd { return nil }
+
+func (m *pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.choices)-1 {
+			m.cursor++
+		}
+	case "enter":
+		m.chosen = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *pickerModel) View() string {
+	nameWidth := 0
+	for _, c := range m.choices {
+		nameWidth = max(nameWidth, lipgloss.Width(c.Name))
+	}
+	lines := make([]string, 0, len(m.choices))
+	for i, c := range m.choices {
+		line := c.Name + strings.Repeat(" ", nameWidth-lipgloss.Width(c.Name))
+		if c.Detail != "" {
+			line += "  " + c.Detail
+		}
+		if i == m.cursor {
+			line = selectedChoiceStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	body := dialogStyle.Render(strings.Join(lines, "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left,
+		headerStyle.Render(m.title),
+		body,
+		hintStyle.Render("enter: connect • ↑/↓: move • q: quit"),
+	)
+}
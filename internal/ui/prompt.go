@@ -0,0 +1,296 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	errNoPrompt     = errors.New("no interactive prompt available")
+	errPromptClosed = errors.New("prompt closed before it was answered")
+	errCanceled     = errors.New("canceled by user")
+)
+
+var (
+	dialogStyle = lipgloss.NewStyle().
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(focusedBorder).
+			Padding(0, 1)
+	hintStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+)
+
+const (
+	promptConfirm = iota
+	promptInput
+	promptChoice
+)
+
+// Prompter forwards questions raised outside the UI, such as host key checks
+// during the SSH handshake, to the attached Bubble Tea program and blocks
+// until the user answers.
+type Prompter struct {
+	mu      sync.Mutex
+	program *tea.Program
+	done    chan struct{}
+}
+
+func NewPrompter() *Prompter {
+	return &Prompter{}
+}
+
+// Confirm asks a yes/no question.
+func (p *Prompter) Confirm(title, message string) (bool, error) {
+	answer, err := p.ask(promptRequest{kind: promptConfirm, title: title, message: message})
+	return answer.confirmed, err
+}
+
+// Challenge asks one or more free-form questions, as sent by a
+// keyboard-interactive server. Answers to questions with echo disabled are
+// typed into masked inputs.
+func (p *Prompter) Challenge(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	if len(questions) == 0 {
+		return nil, nil
+	}
+	title := name
+	if title == "" {
+		title = "Authentication required"
+	}
+	answer, err := p.ask(promptRequest{
+		kind:      promptInput,
+		title:     title,
+		message:   instruction,
+		questions: questions,
+		echos:     echos,
+	})
+	return answer.values, err
+}
+
+// choose offers a list of options, each picked with its key. With allowAll the
+// user can also tick "apply to all", which is reported alongside the choice.
+func (p *Prompter) choose(title, message string, options []promptOption, allowAll bool) (int, bool, error) {
+	answer, err := p.ask(promptRequest{
+		kind:     promptChoice,
+		title:    title,
+		message:  message,
+		options:  options,
+		allowAll: allowAll,
+	})
+	return answer.choice, answer.all, err
+}
+
+// Attach routes prompts to program until Detach is called.
+func (p *Prompter) Attach(program *tea.Program) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.program = program
+	p.done = make(chan struct{})
+}
+
+func (p *Prompter) Detach() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done != nil {
+		close(p.done)
+	}
+	p.program = nil
+	p.done = nil
+}
+
+func (p *Prompter) ask(req promptRequest) (promptAnswer, error) {
+	if p == nil {
+		return promptAnswer{}, errNoPrompt
+	}
+	p.mu.Lock()
+	program, done := p.program, p.done
+	p.mu.Unlock()
+	if program == nil {
+		return promptAnswer{}, errNoPrompt
+	}
+	req.reply = make(chan promptAnswer, 1)
+	go program.Send(promptMsg{req: req})
+	select {
+	case answer := <-req.reply:
+		return answer, answer.err
+	case <-done:
+		return promptAnswer{}, errPromptClosed
+	}
+}
+
+type promptRequest struct {
+	kind      int
+	title     string
+	message   string
+	questions []string
+	echos     []bool
+	options   []promptOption
+	allowAll  bool
+	reply     chan promptAnswer
+}
+
+type promptOption struct {
+	key   string
+	label string
+}
+
+type promptAnswer struct {
+	confirmed bool
+	values    []string
+	choice    int
+	all       bool
+	err       error
+}
+
+type promptMsg struct {
+	req promptRequest
+}
+
+// promptDialog renders a pending promptRequest and collects the answer.
+type promptDialog struct {
+	req    promptRequest
+	inputs []textinput.Model
+	active int
+	all    bool
+}
+
+func newPromptDialog(req promptRequest) *promptDialog {
+	d := &promptDialog{req: req}
+	for i, question := range req.questions {
+		input := textinput.New()
+		input.Prompt = strings.TrimRight(question, " ") + " "
+		if i < len(req.echos) && !req.echos[i] {
+			input.EchoMode = textinput.EchoPassword
+		}
+		d.inputs = append(d.inputs, input)
+	}
+	d.focusInput(0)
+	return d
+}
+
+// handleKey processes a key press and reports whether the dialog is finished.
+func (d *promptDialog) handleKey(msg tea.KeyMsg) bool {
+	switch d.req.kind {
+	case promptInput:
+		return d.handleInputKey(msg)
+	case promptChoice:
+		return d.handleChoiceKey(msg)
+	}
+	switch msg.String() {
+	case "y", "Y":
+		d.answer(promptAnswer{confirmed: true})
+		return true
+	case "n", "N", "esc":
+		d.answer(promptAnswer{confirmed: false})
+		return true
+	}
+	return false
+}
+
+func (d *promptDialog) handleInputKey(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc":
+		d.cancel(errCanceled)
+		return true
+	case "tab", "down":
+		d.focusInput((d.active + 1) % len(d.inputs))
+		return false
+	case "shift+tab", "up":
+		d.focusInput((d.active + len(d.inputs) - 1) % len(d.inputs))
+		return false
+	case "enter":
+		if d.active < len(d.inputs)-1 {
+			d.focusInput(d.active + 1)
+			return false
+		}
+		values := make([]string, len(d.inputs))
+		for i, input := range d.inputs {
+			values[i] = input.Value()
+		}
+		d.answer(promptAnswer{values: values})
+		return true
+	}
+	d.inputs[d.active], _ = d.inputs[d.active].Update(msg)
+	return false
+}
+
+func (d *promptDialog) handleChoiceKey(msg tea.KeyMsg) bool {
+	key := msg.String()
+	switch {
+	case key == "esc":
+		d.cancel(errCanceled)
+		return true
+	case key == "a" && d.req.allowAll:
+		d.all = !d.all
+		return false
+	}
+	for i, option := range d.req.options {
+		if key == option.key {
+			d.answer(promptAnswer{choice: i, all: d.all})
+			return true
+		}
+	}
+	return false
+}
+
+func (d *promptDialog) focusInput(idx int) {
+	if idx < 0 || idx >= len(d.inputs) {
+		return
+	}
+	d.inputs[d.active].Blur()
+	d.active = idx
+	d.inputs[d.active].Focus()
+}
+
+func (d *promptDialog) cancel(err error) {
+	d.answer(promptAnswer{err: err})
+}
+
+func (d *promptDialog) answer(a promptAnswer) {
+	if d.req.reply == nil {
+		return
+	}
+	d.req.reply <- a
+	d.req.reply = nil
+}
+
+func (d *promptDialog) view(width int) string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(d.req.title))
+	if d.req.message != "" {
+		b.WriteString("\n\n" + d.req.message)
+	}
+	switch d.req.kind {
+	case promptInput:
+		b.WriteString("\n")
+		for _, input := range d.inputs {
+			b.WriteString("\n" + input.View())
+		}
+		b.WriteString("\n\n" + hintStyle.Render("enter: submit • tab: next field • esc: cancel"))
+	case promptChoice:
+		b.WriteString("\n")
+		for _, option := range d.req.options {
+			b.WriteString("\n" + headerStyle.Render(option.key) + "  " + option.label)
+		}
+		hint := "esc: cancel"
+		if d.req.allowAll {
+			check := "[ ]"
+			if d.all {
+				check = "[x]"
+			}
+			b.WriteString("\n\n" + check + " apply to all")
+			hint = "a: toggle apply to all • " + hint
+		}
+		b.WriteString("\n\n" + hintStyle.Render(hint))
+	default:
+		b.WriteString("\n\n" + hintStyle.Render("y: yes • n/esc: no"))
+	}
+	style := dialogStyle
+	if width > 0 {
+		style = style.MaxWidth(width)
+	}
+	return style.Render(b.String())
+}
@@ -4,7 +4,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/pkg/sftp"
+	"github.com/m1kkY8/termftp/internal/sftpclient"
 )
 
 type localProvider struct{}
@@ -30,11 +30,15 @@ func (localProvider) ReadDir(path string) ([]entry, error) {
 }
 
 type sftpProvider struct {
-	client *sftp.Client
+	client *sftpclient.Client
 }
 
 func (p *sftpProvider) ReadDir(path string) ([]entry, error) {
-	files, err := p.client.ReadDir(path)
+	remote := p.client.SFTP()
+	if remote == nil {
+		return nil, sftpclient.ErrNotConnected
+	}
+	files, err := remote.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,340 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	entryPending = iota
+	entryRunning
+	entryDone
+	entryFailed
+	entryCanceled
+)
+
+var entryStatusNames = []string{"pending", "running", "done", "failed", "canceled"}
+
+// queueEntry is one enqueued transfer together with its progress.
+type queueEntry struct {
+	id     int
+	task   *transferTask
+	status int
+	state  transferState
+}
+
+func (e *queueEntry) finished() bool {
+	return e.status == entryDone || e.status == entryFailed || e.status == entryCanceled
+}
+
+func (e *queueEntry) statusName() string {
+	if e.status == entryRunning && e.state.paused {
+		return "paused"
+	}
+	return entryStatusNames[e.status]
+}
+
+// transferQueue keeps transfers in the order they will run and remembers
+// finished ones until they are cleared.
+type transferQueue struct {
+	entries []*queueEntry
+	nextID  int
+	cursor  int
+}
+
+func (q *transferQueue) add(task *transferTask) *queueEntry {
+	q.nextID++
+	e := &queueEntry{
+		id:     q.nextID,
+		task:   task,
+		status: entryPending,
+		state: transferState{
+			direction:   task.direction,
+			filename:    task.name,
+			refreshPane: task.refreshPane,
+		},
+	}
+	q.entries = append(q.entries, e)
+	return e
+}
+
+func (q *transferQueue) find(id int) *queueEntry {
+	for _, e := range q.entries {
+		if e.id == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (q *transferQueue) withStatus(status int) []*queueEntry {
+	var result []*queueEntry
+	for _, e := range q.entries {
+		if e.status == status {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (q *transferQueue) selected() *queueEntry {
+	if q.cursor < 0 || q.cursor >= len(q.entries) {
+		return nil
+	}
+	return q.entries[q.cursor]
+}
+
+func (q *transferQueue) moveCursor(delta int) {
+	if len(q.entries) == 0 {
+		q.cursor = 0
+		return
+	}
+	q.cursor = min(max(q.cursor+delta, 0), len(q.entries)-1)
+}
+
+// shift moves the selected pending entry past its pending neighbour, changing
+// the order in which they start.
+func (q *transferQueue) shift(delta int) {
+	i, j := q.cursor, q.cursor+delta
+	if i < 0 || j < 0 || i >= len(q.entries) || j >= len(q.entries) {
+		return
+	}
+	if q.entries[i].status != entryPending || q.entries[j].status != entryPending {
+		return
+	}
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+	q.cursor = j
+}
+
+// remove cancels the selected entry if it is running and drops it from the
+// queue otherwise. A canceled entry stays listed until it finishes.
+func (q *transferQueue) remove() {
+	e := q.selected()
+	if e == nil {
+		return
+	}
+	if e.status == entryRunning {
+		e.task.ctl.cancel()
+		return
+	}
+	q.entries = append(q.entries[:q.cursor], q.entries[q.cursor+1:]...)
+	q.moveCursor(0)
+}
+
+// retry puts the selected failed or canceled entry back into the queue with a
+// fresh task.
+func (q *transferQueue) retry() bool {
+	e := q.selected()
+	if e == nil || (e.status != entryFailed && e.status != entryCanceled) {
+		return false
+	}
+	e.task = e.task.clone()
+	e.status = entryPending
+	e.state = transferState{
+		direction:   e.task.direction,
+		filename:    e.task.name,
+		refreshPane: e.task.refreshPane,
+	}
+	return true
+}
+
+func (q *transferQueue) clearFinished() {
+	kept := q.entries[:0]
+	for _, e := range q.entries {
+		if !e.finished() {
+			kept = append(kept, e)
+		}
+	}
+	q.entries = kept
+	q.moveCursor(0)
+}
+
+func (m *model) enqueue(task *transferTask) tea.Cmd {
+	m.queue.add(task)
+	return m.schedule()
+}
+
+// schedule starts pending entries, in queue order, until the configured
+// number of transfers is running. An entry whose destination overlaps one
+// that is running, or one queued before it, waits: both would write the same
+// part files, and the later one has to land last.
+func (m *model) schedule() tea.Cmd {
+	if m.quitting {
+		return nil
+	}
+	running := len(m.queue.withStatus(entryRunning))
+	var busy []target
+	for _, e := range m.queue.withStatus(entryRunning) {
+		busy = append(busy, e.task.targets()...)
+	}
+	var cmds []tea.Cmd
+	for _, e := range m.queue.entries {
+		if running >= m.transferCfg.concurrent {
+			break
+		}
+		if e.status != entryPending {
+			continue
+		}
+		targets := e.task.targets()
+		blocked := overlapsAny(busy, targets)
+		busy = append(busy, targets...)
+		if blocked {
+			continue
+		}
+		now := time.Now()
+		e.status = entryRunning
+		e.state.active = true
+		e.state.started = now
+		e.state.lastUpdate = now
+		cmds = append(cmds, runEntry(e))
+		running++
+	}
+	if len(cmds) > 0 && !m.ticking {
+		m.ticking = true
+		cmds = append(cmds, m.scheduleProgressTick())
+	}
+	return tea.Batch(cmds...)
+}
+
+// target is a path a task writes at or below, on the local or remote side.
+type target struct {
+	remote bool
+	path   string
+}
+
+func newTarget(fsys fileSystem, path string) target {
+	_, remote := fsys.(remoteFS)
+	return target{remote: remote, path: filepath.Clean(path)}
+}
+
+// contains reports whether b is t or lies below it.
+func (t target) contains(b target) bool {
+	if t.remote != b.remote {
+		return false
+	}
+	return t.path == b.path || strings.HasPrefix(b.path, strings.TrimSuffix(t.path, "/")+"/")
+}
+
+func overlapsAny(busy, targets []target) bool {
+	for _, a := range busy {
+		for _, b := range targets {
+			if a.contains(b) || b.contains(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// targets lists where the task writes: its destination, or the root of each
+// side a sync changes.
+func (t *transferTask) targets() []target {
+	if t.sync == nil {
+		return []target{newTarget(t.dst, t.dstPath)}
+	}
+	var targets []target
+	for _, leg := range t.sync.legs {
+		targets = append(targets, newTarget(leg.dst, leg.dstRoot))
+	}
+	return targets
+}
+
+func runEntry(e *queueEntry) tea.Cmd {
+	id, task := e.id, e.task
+	return func() tea.Msg {
+		return transferDoneMsg{id: id, err: task.run()}
+	}
+}
+
+func (m *model) handleQueueKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m.quit()
+	case "t", "esc":
+		m.showQueue = false
+	case "up", "k":
+		m.queue.moveCursor(-1)
+	case "down", "j":
+		m.queue.moveCursor(1)
+	case "K", "shift+up":
+		m.queue.shift(-1)
+	case "J", "shift+down":
+		m.queue.shift(1)
+	case "x", "delete":
+		m.queue.remove()
+	case " ", "s":
+		if e := m.queue.selected(); e != nil && e.status == entryRunning {
+			e.task.ctl.togglePause()
+			e.state.paused = e.task.ctl.isPaused()
+		}
+	case "+", "=", "-":
+		if e := m.queue.selected(); e != nil && !e.finished() {
+			dir := 1
+			if msg.String() == "-" {
+				dir = -1
+			}
+			e.task.ctl.limiter.step(dir)
+		}
+	case "r":
+		if m.queue.retry() {
+			return m.schedule()
+		}
+	case "c":
+		m.queue.clearFinished()
+	}
+	return nil
+}
+
+// togglePauseAll pauses every running transfer, or resumes them all when
+// they are already paused.
+func (m *model) togglePauseAll() {
+	running := m.queue.withStatus(entryRunning)
+	pause := false
+	for _, e := range running {
+		if !e.task.ctl.isPaused() {
+			pause = true
+		}
+	}
+	for _, e := range running {
+		if pause {
+			e.task.ctl.pause()
+		} else {
+			e.task.ctl.unpause()
+		}
+		e.state.paused = pause
+	}
+}
+
+func (m *model) cancelRunning() {
+	for _, e := range m.queue.withStatus(entryRunning) {
+		e.task.ctl.cancel()
+	}
+}
+
+// quit cancels running transfers and waits for them to clean up before
+// exiting, so partial files are handled per config. Quitting a second time
+// exits immediately.
+func (m *model) quit() tea.Cmd {
+	if m.quitting || len(m.queue.withStatus(entryRunning)) == 0 {
+		return tea.Quit
+	}
+	m.quitting = true
+	m.cancelRunning()
+	return tea.Printf("canceling transfers, press q again to quit now")
+}
+
+func (m *model) queueSummary() string {
+	var parts []string
+	for status, name := range entryStatusNames {
+		if status == entryRunning {
+			continue
+		}
+		if n := len(m.queue.withStatus(status)); n > 0 {
+			parts = append(parts, formatCount(n, name))
+		}
+	}
+	return strings.Join(parts, " • ")
+}
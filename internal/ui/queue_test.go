@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleSerializesOverlappingDestinations(t *testing.T) {
+	cfg := transferConfig{concurrent: 4, progressInterval: time.Millisecond}
+	m := &model{transferCfg: cfg}
+	upload := func(dst string) *queueEntry {
+		return m.queue.add(newTransferTask("Upload", localFS{}, remoteFS{}, "/src/f", dst, paneRemote, cfg))
+	}
+	first := upload("/srv/a/f")
+	second := upload("/srv/a/f")
+	inside := upload("/srv/a/f/x")
+	local := m.queue.add(newTransferTask("Download", remoteFS{}, localFS{}, "/srv/a/f", "/srv/a/f", paneLocal, cfg))
+	other := upload("/srv/b")
+	m.schedule()
+
+	want := map[*queueEntry]int{
+		first:  entryRunning,
+		second: entryPending,
+		inside: entryPending,
+		local:  entryRunning,
+		other:  entryRunning,
+	}
+	for e, status := range want {
+		if e.status != status {
+			t.Errorf("entry %d is %s, want %s", e.id, e.statusName(), entryStatusNames[status])
+		}
+	}
+
+	first.status = entryDone
+	m.schedule()
+	if second.status != entryRunning || inside.status != entryPending {
+		t.Fatalf("after the first finished: second %s, inside %s; want running, pending",
+			second.statusName(), inside.statusName())
+	}
+}
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// resumeSuffix marks the sidecar that records how much of a partial
+// destination is known to be written.
+const resumeSuffix = ".termftp-resume"
+
+// resumePath is the hidden sidecar kept next to path while it is written.
+func resumePath(path string) string {
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, "."+name+resumeSuffix)
+}
+
+// resumeMarker tracks the contiguous prefix of a destination that has been
+// written and persists it in a sidecar file. Parallel streams finish blocks
+// out of order, so neither the size of a partial file nor its last block
+// says whether everything below it arrived; only ranges without gaps move
+// the mark. The sidecar is written at most once per block and only for files
+// larger than that, so small files never pay for it.
+type resumeMarker struct {
+	fs    fileSystem
+	path  string
+	size  int64
+	every int64
+
+	mu      sync.Mutex
+	mark    int64
+	ranges  map[int64]int64
+	saved   int64
+	saving  bool
+	written bool
+	file    transferFile
+}
+
+func newResumeMarker(fs fileSystem, dst string, size, every int64) *resumeMarker {
+	return &resumeMarker{
+		fs:     fs,
+		path:   resumePath(dst),
+		size:   size,
+		every:  maxInt64(every, 1),
+		ranges: make(map[int64]int64),
+	}
+}
+
+// load returns the recorded resume point of a copy of a size-byte source, or
+// zero when there is none. An unusable sidecar is removed so that a fresh copy
+// never inherits it.
+func (m *resumeMarker) load() int64 {
+	f, err := m.fs.Open(m.path)
+	if err != nil {
+		return 0
+	}
+	data, err := io.ReadAll(io.LimitReader(f, 128))
+	f.Close()
+	var mark, size int64
+	if err == nil {
+		_, err = fmt.Sscanf(string(data), "%d %d", &mark, &size)
+	}
+	if err != nil || size != m.size || mark < 0 || mark > size {
+		_ = m.fs.Remove(m.path)
+		return 0
+	}
+	m.written = true
+	return mark
+}
+
+// start records that everything below offset is already in place.
+func (m *resumeMarker) start(offset int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mark = offset
+	m.saved = offset
+}
+
+// discard removes a sidecar that load found but the copy is not going to
+// use.
+func (m *resumeMarker) discard() {
+	if m == nil || !m.written {
+		return
+	}
+	_ = m.fs.Remove(m.path)
+	m.written = false
+}
+
+// advance records that [start, end) has been written, where start is the
+// beginning of the section being copied and end how far it has got.
+func (m *resumeMarker) advance(start, end int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if start > m.mark {
+		m.ranges[start] = maxInt64(m.ranges[start], end)
+		m.mu.Unlock()
+		return
+	}
+	if end <= m.mark {
+		m.mu.Unlock()
+		return
+	}
+	m.mark = end
+	for {
+		next, ok := m.ranges[m.mark]
+		if !ok {
+			break
+		}
+		delete(m.ranges, m.mark)
+		m.mark = maxInt64(m.mark, next)
+	}
+	mark := m.mark
+	if m.saving || mark-m.saved < m.every || mark >= m.size {
+		m.mu.Unlock()
+		return
+	}
+	m.saving = true
+	m.mu.Unlock()
+
+	// Saving is best effort: a sidecar that lags behind only means a
+	// resume repeats some work.
+	err := m.save(mark)
+	m.mu.Lock()
+	m.saving = false
+	if err == nil {
+		m.saved = mark
+	}
+	m.mu.Unlock()
+}
+
+// save overwrites the sidecar with mark. Only one save runs at a time, so the
+// handle needs no lock of its own.
+func (m *resumeMarker) save(mark int64) error {
+	if m.file == nil {
+		f, err := m.fs.Create(m.path)
+		if err != nil {
+			return err
+		}
+		m.file = f
+		m.written = true
+	}
+	// Fixed-width fields let every save overwrite the previous one in place.
+	record := fmt.Sprintf("%020d %020d\n", mark, m.size)
+	if _, err := m.file.WriteAt([]byte(record), 0); err != nil {
+		m.file.Close()
+		m.file = nil
+		return err
+	}
+	return nil
+}
+
+// close releases the sidecar and keeps it for a later resume.
+func (m *resumeMarker) close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file != nil {
+		m.file.Close()
+		m.file = nil
+	}
+}
+
+// remove deletes the sidecar once the destination is complete or gone.
+func (m *resumeMarker) remove() {
+	if m == nil {
+		return
+	}
+	m.close()
+	m.discard()
+}
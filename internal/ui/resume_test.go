@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeMarkerSkipsHoles(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "file")
+	m := newResumeMarker(localFS{}, dst, 100, 10)
+
+	// Blocks 20-40 and 40-60 finish while block 10-20 is still stuck.
+	m.advance(0, 10)
+	m.advance(20, 30)
+	m.advance(40, 60)
+	m.advance(20, 40)
+	m.advance(10, 15)
+	m.close()
+
+	if got := newResumeMarker(localFS{}, dst, 100, 10).load(); got != 10 {
+		t.Fatalf("resume point = %d, want 10: only the gap-free prefix may be recorded", got)
+	}
+
+	m.advance(10, 20)
+	m.close()
+	if got := newResumeMarker(localFS{}, dst, 100, 10).load(); got != 60 {
+		t.Fatalf("resume point = %d, want 60 once the hole is filled", got)
+	}
+}
+
+func TestResumeMarkerRejectsOtherSource(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "file")
+	m := newResumeMarker(localFS{}, dst, 100, 10)
+	m.advance(0, 50)
+	m.close()
+
+	if got := newResumeMarker(localFS{}, dst, 200, 10).load(); got != 0 {
+		t.Fatalf("resume point = %d for a source of another size, want 0", got)
+	}
+	if got := newResumeMarker(localFS{}, dst, 100, 10).load(); got != 0 {
+		t.Fatalf("resume point = %d after a mismatched sidecar, want it removed", got)
+	}
+}
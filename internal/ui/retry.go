@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/m1kkY8/termftp/internal/sftpclient"
+)
+
+const (
+	retryMinDelay = time.Second
+	retryMaxDelay = 30 * time.Second
+)
+
+// copySection copies [off, off+length) and retries transient failures with
+// exponential backoff. Each retry continues from the last byte the section
+// wrote and, when the failure may have killed the file handles, reopens them
+// first.
+func (j *transferJob) copySection(off, length int64, buffer []byte) error {
+	start, end := off, off+length
+	delay := retryMinDelay
+	gen := 0
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			if err := j.ctl.sleep(delay); err != nil {
+				return err
+			}
+			delay = min(delay*2, retryMaxDelay)
+			if err := j.reopenHandles(gen); err != nil {
+				if attempt >= j.attempts || !isTransient(err) || j.ctl.canceled() {
+					return err
+				}
+				continue
+			}
+		}
+		var readerAt io.ReaderAt
+		var writerAt io.WriterAt
+		readerAt, writerAt, gen = j.handles()
+		reader := io.NewSectionReader(readerAt, off, end-off)
+		writer := &writerAtSection{WriterAt: writerAt, offset: off, start: start, marker: j.marker}
+		_, err := io.CopyBuffer(&countingWriter{dst: writer, job: j}, reader, buffer)
+		off = writer.offset
+		if err == nil || err == io.EOF {
+			return nil
+		}
+		if attempt >= j.attempts || !isTransient(err) || j.ctl.canceled() {
+			return err
+		}
+	}
+}
+
+// handles returns the current file handles and their generation, which
+// changes every time they are reopened.
+func (j *transferJob) handles() (io.ReaderAt, io.WriterAt, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.readerAt, j.writerAt, j.gen
+}
+
+// reopenHandles replaces the file handles of generation gen. Sections that
+// failed on handles another section already replaced just pick up the new
+// ones.
+func (j *transferJob) reopenHandles(gen int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if gen != j.gen || j.reopen == nil {
+		return nil
+	}
+	readerAt, writerAt, closers, err := j.reopen()
+	if err != nil {
+		return err
+	}
+	for _, c := range j.closers {
+		if c != nil {
+			c.Close()
+		}
+	}
+	j.readerAt, j.writerAt, j.closers = readerAt, writerAt, closers
+	j.gen++
+	return nil
+}
+
+// isTransient reports whether err may go away on its own: a lost or reset
+// connection or an unexpected EOF. A generic SFTP failure status is not
+// retried, since servers also use it for a full disk or a denied write, and
+// neither is a closed handle, which is how cancel and pause stop a copy.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var status *sftp.StatusError
+	if errors.As(err, &status) {
+		switch status.FxCode() {
+		case sftp.ErrSSHFxNoConnection, sftp.ErrSSHFxConnectionLost:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, target := range []error{
+		sftp.ErrSSHFxConnectionLost,
+		sftp.ErrSSHFxNoConnection,
+		sftpclient.ErrNotConnected,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+		syscall.ECONNRESET,
+		syscall.EPIPE,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
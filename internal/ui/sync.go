@@ -0,0 +1,429 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+const (
+	syncCreate = iota
+	syncUpdate
+	syncDelete
+	syncConflict
+)
+
+var syncOpMarks = []string{"+", "~", "-", "!"}
+
+// syncAction is one line of a sync plan: a path, relative to the roots, that
+// is created, updated or deleted on one side. In a two-way plan toLocal tells
+// which side that is, and a conflict carries both sides' entries, a nil one
+// for a side where the path is missing, until the user picks one.
+type syncAction struct {
+	op      int
+	rel     string
+	dir     bool
+	size    int64
+	toLocal bool
+	reason  string
+	choice  int
+	local   *treeEntry
+	remote  *treeEntry
+}
+
+const (
+	keepNeither = iota
+	keepLocal
+	keepRemote
+)
+
+// syncLeg is the part of a plan that changes one side: deletes first, so
+// that a file can replace a directory of the same name, then the copies.
+// hasDir tracks which directories are already in dirs.
+type syncLeg struct {
+	src     fileSystem
+	dst     fileSystem
+	dstRoot string
+	items   []transferItem
+	dirs    []transferItem
+	hasDir  map[string]bool
+	deletes []string
+}
+
+func newSyncLeg(src, dst fileSystem, dstRoot string) *syncLeg {
+	return &syncLeg{
+		src:     src,
+		dst:     dst,
+		dstRoot: dstRoot,
+		dirs:    []transferItem{{dst: dstRoot}},
+		hasDir:  map[string]bool{dstRoot: true},
+	}
+}
+
+func (l *syncLeg) copy(rel string, source treeEntry) {
+	target := filepath.Join(l.dstRoot, rel)
+	if source.info.IsDir() {
+		l.hasDir[target] = true
+		l.dirs = append(l.dirs, newTransferItem(source.path, target, source.info))
+		return
+	}
+	// Copied directories come before their files; other parents only need
+	// to exist.
+	if parent := filepath.Dir(target); !l.hasDir[parent] {
+		l.hasDir[parent] = true
+		l.dirs = append(l.dirs, transferItem{dst: parent})
+	}
+	l.items = append(l.items, newTransferItem(source.path, target, source.info))
+}
+
+func (l *syncLeg) remove(rel string) {
+	l.deletes = append(l.deletes, filepath.Join(l.dstRoot, rel))
+}
+
+// removeDeleted removes the planned deletes, children before parents.
+// Entries already gone, say on a retry, are fine.
+func (l *syncLeg) removeDeleted() error {
+	for i := len(l.deletes) - 1; i >= 0; i-- {
+		path := l.deletes[i]
+		if err := l.dst.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("delete %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// syncPlan is what a sync will do, shown for review and then run as a single
+// transferTask. A one-way plan makes dstRoot mirror srcRoot in one leg; a
+// two-way plan has a leg per side, srcRoot being the local and dstRoot the
+// remote directory, and keeps state between runs.
+type syncPlan struct {
+	direction   string
+	srcRoot     string
+	dstRoot     string
+	refreshPane int
+	actions     []syncAction
+	legs        []*syncLeg
+	state       *syncState
+	cursor      int
+}
+
+type syncPlanMsg struct {
+	plan *syncPlan
+	err  error
+}
+
+// treeEntry is a file or directory found by scanTree.
+type treeEntry struct {
+	path string
+	info os.FileInfo
+}
+
+// scanTree lists the regular files and directories below root by path
+// relative to it, along with the relative paths in walk order, parents first.
+// Other entries, such as symlinks, are left out.
+func scanTree(fsys fileSystem, root string) (map[string]treeEntry, []string, error) {
+	tree := map[string]treeEntry{}
+	var order []string
+	err := fsys.Walk(root, func(path string, info os.FileInfo) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." || (!info.IsDir() && !info.Mode().IsRegular()) {
+			return nil
+		}
+		tree[rel] = treeEntry{path: path, info: info}
+		order = append(order, rel)
+		return nil
+	})
+	return tree, order, err
+}
+
+// sameKind reports whether a and b are both directories or both files.
+func sameKind(a, b os.FileInfo) bool {
+	return a.IsDir() == b.IsDir() && a.Mode().IsRegular() == b.Mode().IsRegular()
+}
+
+// sameTime compares modification times at the one-second resolution SFTP
+// carries.
+func sameTime(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// planSync compares srcRoot with dstRoot recursively. Files are updated when
+// their size differs, or their modification time or checksum as configured.
+// Destination entries of the wrong kind are always replaced; those missing
+// from the source are only deleted when the config asks for it. A missing
+// dstRoot is created.
+func planSync(direction string, src, dst fileSystem, srcRoot, dstRoot string, refreshPane int, cfg transferConfig) (*syncPlan, error) {
+	srcTree, srcOrder, err := scanTree(src, srcRoot)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", srcRoot, err)
+	}
+	dstTree, dstOrder, err := scanTree(dst, dstRoot)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("scan %s: %w", dstRoot, err)
+	}
+	leg := newSyncLeg(src, dst, dstRoot)
+	p := &syncPlan{
+		direction:   direction,
+		srcRoot:     srcRoot,
+		dstRoot:     dstRoot,
+		refreshPane: refreshPane,
+		legs:        []*syncLeg{leg},
+	}
+
+	gone := map[string]bool{}
+	for _, rel := range dstOrder {
+		existing := dstTree[rel]
+		source, ok := srcTree[rel]
+		if ok && sameKind(source.info, existing.info) {
+			continue
+		}
+		if !ok && !cfg.syncDelete && !gone[filepath.Dir(rel)] {
+			continue
+		}
+		gone[rel] = true
+		p.actions = append(p.actions, syncAction{op: syncDelete, rel: rel, dir: existing.info.IsDir()})
+		leg.remove(rel)
+	}
+
+	for _, rel := range srcOrder {
+		source := srcTree[rel]
+		existing, exists := dstTree[rel]
+		exists = exists && !gone[rel]
+		if source.info.IsDir() {
+			leg.copy(rel, source)
+			if !exists {
+				p.actions = append(p.actions, syncAction{op: syncCreate, rel: rel, dir: true})
+			}
+			continue
+		}
+		op := syncCreate
+		if exists {
+			changed, err := contentChanged(src, dst, source, existing, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("compare %s: %w", rel, err)
+			}
+			if !changed {
+				continue
+			}
+			op = syncUpdate
+		}
+		p.actions = append(p.actions, syncAction{op: op, rel: rel, size: source.info.Size()})
+		leg.copy(rel, source)
+	}
+	return p, nil
+}
+
+// contentChanged compares by size, then by modification time or, when the
+// config asks for it, by checksum. Files read back for a checksum count
+// against the global bandwidth limit.
+func contentChanged(src, dst fileSystem, source, existing treeEntry, cfg transferConfig) (bool, error) {
+	if source.info.Size() != existing.info.Size() {
+		return true, nil
+	}
+	if !cfg.syncChecksum {
+		return !sameTime(source.info.ModTime(), existing.info.ModTime()), nil
+	}
+	var throttle func(n int) error
+	if cfg.limiter != nil {
+		throttle = func(n int) error { return cfg.limiter.wait(context.Background(), n) }
+	}
+	srcSum, err := src.Checksum(source.path, throttle)
+	if err != nil {
+		return false, err
+	}
+	dstSum, err := dst.Checksum(existing.path, throttle)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(srcSum, dstSum), nil
+}
+
+// task turns the plan into a transfer. The plan already decided what to
+// overwrite, and modification times are kept so that the next comparison
+// sees the copies as up to date. Nothing is resumed: an outdated destination
+// may be a prefix of the new source without being part of it, and stamping
+// the source's time on such a mix would hide it from every later sync.
+func (p *syncPlan) task(cfg transferConfig) *transferTask {
+	p.resolveConflicts()
+	cfg.conflict = config.ConflictOverwrite
+	cfg.preserveTimes = true
+	cfg.resume = false
+	first := p.legs[0]
+	t := newTransferTask("Sync", first.src, first.dst, p.srcRoot, p.dstRoot, p.refreshPane, cfg)
+	t.sync = p
+	return t
+}
+
+// runSync works through the plan's legs in turn and then records the state a
+// two-way sync compares against next time.
+func (t *transferTask) runSync() error {
+	var items []transferItem
+	for _, leg := range t.sync.legs {
+		items = append(items, leg.items...)
+	}
+	t.setTotals(items)
+	for _, leg := range t.sync.legs {
+		t.src, t.dst = leg.src, leg.dst
+		_, t.atomic = leg.dst.(remoteFS)
+		if err := leg.removeDeleted(); err != nil {
+			return err
+		}
+		if err := t.copyAll(leg.items, leg.dirs); err != nil {
+			return err
+		}
+	}
+	if t.sync.state == nil {
+		return nil
+	}
+	return t.sync.saveState()
+}
+
+// summary counts the plan's actions and the bytes it sends.
+func (p *syncPlan) summary() string {
+	counts := make([]int, len(syncOpMarks))
+	var send int64
+	for _, a := range p.actions {
+		counts[a.op]++
+		send += a.size
+	}
+	summary := fmt.Sprintf("%d to create • %d to update • %d to delete • %s to send",
+		counts[syncCreate], counts[syncUpdate], counts[syncDelete], formatBytes(send))
+	if counts[syncConflict] > 0 {
+		summary += fmt.Sprintf(" • %d conflicts", counts[syncConflict])
+	}
+	return summary
+}
+
+func (p *syncPlan) moveCursor(delta int) {
+	p.cursor = min(max(p.cursor+delta, 0), max(len(p.actions)-1, 0))
+}
+
+// startSync compares the local and remote working directories in the
+// background; upload mirrors local to remote, otherwise remote to local.
+func (m *model) startSync(upload bool) tea.Cmd {
+	if len(m.panes) < 2 || m.remote() == nil {
+		return tea.Printf("remote client unavailable")
+	}
+	local, remote := m.panes[paneLocal], m.panes[paneRemote]
+	direction, refresh := "local → remote", paneRemote
+	var src, dst fileSystem = localFS{}, remoteFS{client: m.client}
+	srcRoot, dstRoot := local.cwd, remote.cwd
+	if !upload {
+		direction, refresh = "remote → local", paneLocal
+		src, dst = dst, src
+		srcRoot, dstRoot = dstRoot, srcRoot
+	}
+	cfg := m.transferCfg
+	plan := func() tea.Msg {
+		p, err := planSync(direction, src, dst, srcRoot, dstRoot, refresh, cfg)
+		return syncPlanMsg{plan: p, err: err}
+	}
+	return tea.Batch(tea.Printf("comparing %s with %s", srcRoot, dstRoot), plan)
+}
+
+func (m *model) handleSyncPlan(msg syncPlanMsg) tea.Cmd {
+	if msg.err != nil {
+		return tea.Printf("sync failed: %v", msg.err)
+	}
+	if len(msg.plan.actions) == 0 {
+		return tea.Printf("already in sync: %s", msg.plan.dstRoot)
+	}
+	m.syncPlan = msg.plan
+	return nil
+}
+
+func (m *model) handleSyncKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc", "n", "q":
+		m.syncPlan = nil
+	case "up", "k":
+		m.syncPlan.moveCursor(-1)
+	case "down", "j":
+		m.syncPlan.moveCursor(1)
+	case "pgup":
+		m.syncPlan.moveCursor(-10)
+	case "pgdown":
+		m.syncPlan.moveCursor(10)
+	case "l", "r", "s":
+		m.syncPlan.choose(m.syncPlan.cursor, keepChoices[msg.String()])
+	case "L", "R", "S":
+		for i := range m.syncPlan.actions {
+			m.syncPlan.choose(i, keepChoices[strings.ToLower(msg.String())])
+		}
+	case "enter", "y":
+		plan := m.syncPlan
+		m.syncPlan = nil
+		return m.enqueue(plan.task(m.transferCfg))
+	}
+	return nil
+}
+
+// renderSyncPlan lists the pending plan in place of the panes, scrolled to
+// keep the cursor visible.
+func (m *model) renderSyncPlan(width int) string {
+	if width <= 0 {
+		width = max(20, m.width)
+	}
+	p := m.syncPlan
+	rows := max(5, m.height-12)
+	start := min(max(p.cursor-rows/2, 0), max(len(p.actions)-rows, 0))
+	end := min(start+rows, len(p.actions))
+	arrow := "→"
+	if p.state != nil {
+		arrow = "↔"
+	}
+	lines := []string{
+		fmt.Sprintf("%s %s %s", p.srcRoot, arrow, p.dstRoot),
+		p.summary(),
+		"",
+	}
+	for i := start; i < end; i++ {
+		a := p.actions[i]
+		name := a.rel
+		if a.dir {
+			name += string(filepath.Separator)
+		}
+		line := fmt.Sprintf("%s %s", syncOpMarks[a.op], name)
+		if p.state != nil && a.op != syncConflict {
+			side := "→"
+			if a.toLocal {
+				side = "←"
+			}
+			line = side + " " + line
+		}
+		if a.size > 0 {
+			line += " " + hintStyle.Render(formatBytes(a.size))
+		}
+		if a.op == syncConflict {
+			line = "  " + line + " " + errorStyle.Render(a.reason) + " " + hintStyle.Render(keepLabels[a.choice])
+		}
+		if i == p.cursor {
+			line = headerStyle.Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	hint := "enter/y: run • j/k scroll • esc/n: cancel"
+	if p.state != nil {
+		hint = "l/r: keep local/remote • s: skip • L/R/S: all conflicts • " + hint
+	}
+	lines = append(lines, "", hintStyle.Render(hint))
+	panel := transferPaneStyle.Width(max(20, width-2)).Render(strings.Join(lines, "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, headerStyle.Render("Sync "+p.direction), panel)
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m1kkY8/termftp/internal/config"
+)
+
+var testMtime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// writeTree creates files below root, keyed by relative path with their
+// content; a key ending in "/" is a directory. Every file gets testMtime.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if strings.HasSuffix(rel, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, path, testMtime)
+	}
+}
+
+func touch(t *testing.T, path string, mtime time.Time) {
+	t.Helper()
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// actionList renders a plan's actions as "mark path" lines in plan order,
+// with the side a two-way action changes in front.
+func actionList(p *syncPlan) []string {
+	var lines []string
+	for _, a := range p.actions {
+		line := syncOpMarks[a.op] + " " + a.rel
+		if p.state != nil && a.op != syncConflict {
+			side := "→ "
+			if a.toLocal {
+				side = "← "
+			}
+			line = side + line
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestPlanSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst map[string]string
+		cfg      transferConfig
+		touchDst map[string]time.Time
+		want     []string
+		deletes  []string
+	}{
+		{
+			name: "extra entries kept by default",
+			src:  map[string]string{"a": "same"},
+			dst:  map[string]string{"a": "same", "extra": "x", "old/": "", "old/f": "y"},
+		},
+		{
+			name:    "extra entries deleted when asked",
+			src:     map[string]string{"a": "same"},
+			dst:     map[string]string{"a": "same", "extra": "x", "old/": "", "old/f": "y"},
+			cfg:     transferConfig{syncDelete: true},
+			want:    []string{"- extra", "- old", "- old/f"},
+			deletes: []string{"extra", "old", "old/f"},
+		},
+		{
+			name:    "wrong kind replaced even without deletes",
+			src:     map[string]string{"x": "file", "d/": "", "d/f": "f"},
+			dst:     map[string]string{"x/": "", "x/inner": "i", "d": "file"},
+			want:    []string{"- d", "- x", "- x/inner", "+ d", "+ d/f", "+ x"},
+			deletes: []string{"d", "x", "x/inner"},
+		},
+		{
+			name: "size and mtime changes update",
+			src:  map[string]string{"grown": "longer", "touched": "same", "equal": "same", "new": "n"},
+			dst:  map[string]string{"grown": "short", "touched": "same", "equal": "same"},
+			touchDst: map[string]time.Time{
+				"touched": testMtime.Add(-time.Hour),
+			},
+			want: []string{"~ grown", "+ new", "~ touched"},
+		},
+		{
+			name:     "checksums ignore mtime",
+			src:      map[string]string{"touched": "same", "edited": "abcd"},
+			dst:      map[string]string{"touched": "same", "edited": "abce"},
+			cfg:      transferConfig{syncChecksum: true},
+			touchDst: map[string]time.Time{"touched": testMtime.Add(-time.Hour)},
+			want:     []string{"~ edited"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			writeTree(t, src, tt.src)
+			writeTree(t, dst, tt.dst)
+			for rel, mtime := range tt.touchDst {
+				touch(t, filepath.Join(dst, rel), mtime)
+			}
+
+			p, err := planSync("local → remote", localFS{}, localFS{}, src, dst, paneRemote, tt.cfg)
+			if err != nil {
+				t.Fatalf("planSync: %v", err)
+			}
+			if got := actionList(p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actions = %q, want %q", got, tt.want)
+			}
+			var deletes []string
+			for _, path := range p.legs[0].deletes {
+				rel, _ := filepath.Rel(dst, path)
+				deletes = append(deletes, rel)
+			}
+			if !reflect.DeepEqual(deletes, tt.deletes) {
+				t.Errorf("deletes = %q, want %q", deletes, tt.deletes)
+			}
+		})
+	}
+}
+
+func TestPlanSyncMissingDestination(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "missing")
+	writeTree(t, src, map[string]string{"a": "1", "sub/": "", "sub/b": "2"})
+
+	p, err := planSync("local → remote", localFS{}, localFS{}, src, dst, paneRemote, transferConfig{})
+	if err != nil {
+		t.Fatalf("planSync: %v", err)
+	}
+	if want := []string{"+ a", "+ sub", "+ sub/b"}; !reflect.DeepEqual(actionList(p), want) {
+		t.Fatalf("actions = %q, want %q", actionList(p), want)
+	}
+	leg := p.legs[0]
+	var dirs []string
+	for _, d := range leg.dirs {
+		dirs = append(dirs, d.dst)
+	}
+	if want := []string{dst, filepath.Join(dst, "sub")}; !reflect.DeepEqual(dirs, want) {
+		t.Fatalf("dirs = %q, want each directory once: %q", dirs, want)
+	}
+}
+
+func TestSyncTaskNeverResumes(t *testing.T) {
+	dir := t.TempDir()
+	p := &syncPlan{srcRoot: dir, dstRoot: dir, legs: []*syncLeg{newSyncLeg(localFS{}, localFS{}, dir)}}
+	task := p.task(transferConfig{resume: true, conflict: config.ConflictAsk})
+	if task.cfg.resume || task.cfg.conflict != config.ConflictOverwrite || !task.cfg.preserveTimes {
+		t.Fatalf("sync task config = resume %v, conflict %q, preserveTimes %v; want false, overwrite, true",
+			task.cfg.resume, task.cfg.conflict, task.cfg.preserveTimes)
+	}
+}
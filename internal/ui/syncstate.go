@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// syncRecord is what a path looked like on both sides after it was last
+// synced. ModTime is in Unix seconds, the resolution SFTP carries.
+type syncRecord struct {
+	Dir     bool  `json:"dir,omitempty"`
+	Size    int64 `json:"size,omitempty"`
+	ModTime int64 `json:"mtime,omitempty"`
+}
+
+func newSyncRecord(info os.FileInfo) syncRecord {
+	if info.IsDir() {
+		return syncRecord{Dir: true}
+	}
+	return syncRecord{Size: info.Size(), ModTime: info.ModTime().Unix()}
+}
+
+func (r syncRecord) matches(info os.FileInfo) bool {
+	if r.Dir || info.IsDir() {
+		return r.Dir == info.IsDir()
+	}
+	return info.Mode().IsRegular() && r.Size == info.Size() && r.ModTime == info.ModTime().Unix()
+}
+
+// syncState is the JSON file a two-way sync keeps per pair of directories,
+// listing every path by its relative name as of the last successful run.
+type syncState struct {
+	path   string
+	Local  string                `json:"local"`
+	Remote string                `json:"remote"`
+	Files  map[string]syncRecord `json:"files"`
+}
+
+// loadSyncState reads the state kept in dir for localRoot and remoteRoot. A
+// pair that was never synced starts out empty.
+func loadSyncState(dir, localRoot, remoteRoot string) (*syncState, error) {
+	if dir == "" {
+		return nil, errors.New("no sync state directory configured")
+	}
+	sum := sha256.Sum256([]byte(localRoot + "\x00" + remoteRoot))
+	s := &syncState{
+		path:   filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		Local:  localRoot,
+		Remote: remoteRoot,
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read sync state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("parse sync state %s: %w", s.path, err)
+		}
+	}
+	if s.Files == nil {
+		s.Files = map[string]syncRecord{}
+	}
+	return s, nil
+}
+
+// update records every path both sides now agree on. Paths they disagree on,
+// such as a skipped conflict, keep their previous record so that the next run
+// sees the same changes again.
+func (s *syncState) update(localTree, remoteTree map[string]treeEntry) {
+	files := map[string]syncRecord{}
+	for rel, l := range localTree {
+		if r, ok := remoteTree[rel]; ok && agree(l.info, r.info) {
+			files[rel] = newSyncRecord(l.info)
+		} else if rec, ok := s.Files[rel]; ok {
+			files[rel] = rec
+		}
+	}
+	for rel, rec := range s.Files {
+		if _, ok := remoteTree[rel]; ok {
+			if _, ok := localTree[rel]; !ok {
+				files[rel] = rec
+			}
+		}
+	}
+	s.Files = files
+}
+
+// save writes the state through a temporary file so that a crash never leaves
+// a truncated one behind.
+func (s *syncState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("save sync state: %w", err)
+	}
+	return nil
+}
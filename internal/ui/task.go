@@ -0,0 +1,433 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// transferItem is a single file copy within a task.
+type transferItem struct {
+	src     string
+	dst     string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	atime   time.Time
+}
+
+func newTransferItem(src, dst string, info os.FileInfo) transferItem {
+	return transferItem{
+		src:     src,
+		dst:     dst,
+		size:    info.Size(),
+		mode:    info.Mode(),
+		modTime: info.ModTime(),
+		atime:   accessTime(info),
+	}
+}
+
+// transferTask copies one selection, a file or a whole directory tree, from
+// src to dst, one file at a time through transferJob. A sync task works
+// through its plan instead.
+type transferTask struct {
+	direction   string
+	name        string
+	src         fileSystem
+	dst         fileSystem
+	srcPath     string
+	dstPath     string
+	refreshPane int
+	cfg         transferConfig
+	ctl         *transferControl
+	conflictAll string
+	atomic      bool
+	sync        *syncPlan
+
+	files      atomic.Int64
+	filesDone  atomic.Int64
+	totalBytes atomic.Int64
+	doneBytes  atomic.Int64
+	skipped    atomic.Int64
+	saved      atomic.Int64
+
+	mu          sync.Mutex
+	current     *transferJob
+	currentName string
+}
+
+// taskProgress is a point-in-time view of a task for the UI.
+type taskProgress struct {
+	files           int
+	filesDone       int
+	total           int64
+	transferred     int64
+	skipped         int64
+	saved           int64
+	file            string
+	fileTotal       int64
+	fileTransferred int64
+}
+
+func newTransferTask(direction string, src, dst fileSystem, srcPath, dstPath string, refreshPane int, cfg transferConfig) *transferTask {
+	// Uploads land under a temporary name so that consumers on the server
+	// never pick up a half-written file.
+	_, usePart := dst.(remoteFS)
+	return &transferTask{
+		direction:   direction,
+		name:        filepath.Base(srcPath),
+		src:         src,
+		dst:         dst,
+		srcPath:     srcPath,
+		dstPath:     dstPath,
+		refreshPane: refreshPane,
+		cfg:         cfg,
+		ctl:         newTransferControl(cfg),
+		atomic:      usePart,
+	}
+}
+
+// partSuffix marks the temporary file an atomic upload writes into.
+const partSuffix = ".termftp-part"
+
+// partPath is the hidden temporary name used for path while it is written.
+func partPath(path string) string {
+	dir, name := filepath.Split(path)
+	return filepath.Join(dir, "."+name+partSuffix)
+}
+
+// clone returns a fresh, not yet started task for the same selection.
+func (t *transferTask) clone() *transferTask {
+	c := newTransferTask(t.direction, t.src, t.dst, t.srcPath, t.dstPath, t.refreshPane, t.cfg)
+	c.ctl.limiter.setLimit(t.ctl.limiter.limit())
+	c.sync = t.sync
+	return c
+}
+
+func (t *transferTask) run() error {
+	if t.sync != nil {
+		return t.runSync()
+	}
+	items, dirs, err := t.plan()
+	if err != nil {
+		return err
+	}
+	return t.copyAll(items, dirs)
+}
+
+// copyAll creates dirs, parents first, and copies items into them.
+func (t *transferTask) copyAll(items, dirs []transferItem) error {
+	for _, dir := range dirs {
+		if err := t.dst.MkdirAll(dir.dst); err != nil {
+			return fmt.Errorf("create directory %s: %w", dir.dst, err)
+		}
+	}
+	for _, item := range items {
+		if err := t.ctl.wait(); err != nil {
+			return err
+		}
+		dst, skip, err := t.resolveConflict(item)
+		if err != nil {
+			return fmt.Errorf("%s: %w", item.src, err)
+		}
+		if skip {
+			t.filesDone.Add(1)
+			t.doneBytes.Add(item.size)
+			t.skipped.Add(item.size)
+			continue
+		}
+		item.dst = dst
+		if err := t.copyFile(item); err != nil {
+			return fmt.Errorf("%s: %w", item.src, err)
+		}
+		t.filesDone.Add(1)
+		t.doneBytes.Add(item.size)
+	}
+	// Writing files touches their directories, so directory metadata goes
+	// last and children before parents.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i].src == "" {
+			continue
+		}
+		if err := t.preserveMetadata(dirs[i].dst, dirs[i]); err != nil {
+			return fmt.Errorf("%s: %w", dirs[i].src, err)
+		}
+	}
+	return nil
+}
+
+// plan lists the files to copy and the destination directories to create,
+// parents first. A plain file yields a single item. The destination's parent
+// comes first among the directories and, having no source, keeps its own
+// metadata.
+func (t *transferTask) plan() ([]transferItem, []transferItem, error) {
+	info, err := t.src.Stat(t.srcPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []transferItem
+	dirs := []transferItem{{dst: filepath.Dir(t.dstPath)}}
+	if !info.IsDir() {
+		items = append(items, newTransferItem(t.srcPath, t.dstPath, info))
+	} else {
+		err = t.src.Walk(t.srcPath, func(path string, info os.FileInfo) error {
+			rel, err := filepath.Rel(t.srcPath, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(t.dstPath, rel)
+			switch {
+			case info.IsDir():
+				dirs = append(dirs, newTransferItem(path, target, info))
+			case info.Mode().IsRegular():
+				items = append(items, newTransferItem(path, target, info))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	t.setTotals(items)
+	return items, dirs, nil
+}
+
+func (t *transferTask) setTotals(items []transferItem) {
+	var total int64
+	for _, item := range items {
+		total += item.size
+	}
+	t.files.Store(int64(len(items)))
+	t.totalBytes.Store(total)
+}
+
+func (t *transferTask) copyFile(item transferItem) error {
+	target := item.dst
+	// A delta download patches the existing file in place, and a failed run
+	// leaves it for the next comparison to repair. Atomic uploads assemble
+	// the delta in the temporary file like any other upload.
+	delta := t.useDelta(item)
+	if t.atomic {
+		item.dst = partPath(target)
+	}
+	inPlace := delta && !t.atomic
+	src, err := t.src.Open(item.src)
+	if err != nil {
+		return fmt.Errorf("open source: %w", err)
+	}
+	var dst transferFile
+	var offset int64
+	var blocks []int64
+	var marker *resumeMarker
+	if delta {
+		dst, blocks, err = t.openDelta(item, target)
+	} else {
+		dst, offset, marker, err = t.openDestination(item, src)
+	}
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("create destination: %w", err)
+	}
+	job := newTransferJob(t.ctl, src, dst, offset, item.size, []io.Closer{src, dst}, t.cfg)
+	job.marker = marker
+	if delta {
+		job.blockSize = deltaBlockSize
+		job.blocks = blocks
+		offset = item.size - changedBytes(blocks, item.size)
+		job.transferred.Store(offset)
+		t.saved.Add(offset)
+	}
+	t.skipped.Add(offset)
+	job.reopen = func() (io.ReaderAt, io.WriterAt, []io.Closer, error) {
+		src, err := t.src.Open(item.src)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		dst, err := t.dst.OpenWrite(item.dst)
+		if err != nil {
+			src.Close()
+			return nil, nil, nil, err
+		}
+		return src, dst, []io.Closer{src, dst}, nil
+	}
+	t.setCurrent(job, filepath.Base(item.src))
+	err = job.run()
+	if cerr := job.close(); err == nil {
+		err = cerr
+	}
+	if err == nil && t.cfg.verify {
+		t.setCurrent(nil, "verifying "+filepath.Base(item.src))
+		err = t.verify(item, inPlace)
+	}
+	if err == nil {
+		err = t.preserveMetadata(item.dst, item)
+	}
+	if err == nil && item.dst != target {
+		if err = t.dst.Rename(item.dst, target); err != nil {
+			err = fmt.Errorf("rename into place: %w", err)
+		}
+	}
+	t.setCurrent(nil, "")
+	switch {
+	case err == nil:
+		marker.remove()
+	case t.ctl.canceled():
+		err = t.ctl.ctx.Err()
+		if !t.cfg.keepPartial && !inPlace {
+			_ = t.dst.Remove(item.dst)
+			marker.remove()
+		} else {
+			marker.close()
+		}
+	default:
+		marker.close()
+	}
+	return err
+}
+
+// preserveMetadata gives path the permission bits and timestamps of item's
+// source, as far as the config asks for them.
+func (t *transferTask) preserveMetadata(path string, item transferItem) error {
+	if t.cfg.preserveMode {
+		if err := t.dst.Chmod(path, item.mode.Perm()); err != nil {
+			return fmt.Errorf("preserve permissions: %w", err)
+		}
+	}
+	if t.cfg.preserveTimes {
+		if err := t.dst.Chtimes(path, item.atime, item.modTime); err != nil {
+			return fmt.Errorf("preserve times: %w", err)
+		}
+	}
+	return nil
+}
+
+// verify compares the SHA-256 of item's source and destination, under the
+// task's bandwidth limits where a side has to be read back. A corrupt
+// destination is removed so that a retry does not resume from it, unless it
+// was patched in place, where the next comparison finds the bad blocks anyway.
+func (t *transferTask) verify(item transferItem, inPlace bool) error {
+	var srcSum []byte
+	var srcErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srcSum, srcErr = t.src.Checksum(item.src, t.ctl.throttle)
+	}()
+	dstSum, err := t.dst.Checksum(item.dst, t.ctl.throttle)
+	<-done
+	if srcErr != nil {
+		return fmt.Errorf("checksum source: %w", srcErr)
+	}
+	if err != nil {
+		return fmt.Errorf("checksum destination: %w", err)
+	}
+	if !bytes.Equal(srcSum, dstSum) {
+		if !inPlace {
+			_ = t.dst.Remove(item.dst)
+		}
+		return fmt.Errorf("checksum mismatch: source %x, destination %x", srcSum, dstSum)
+	}
+	return nil
+}
+
+// resumeTail is how much of the data before the resume point is compared
+// with the source when verifying a partial destination.
+const resumeTail = 1024 * 1024
+
+// openDestination opens item's destination for writing. A partial file left
+// by an earlier attempt is reused and the returned offset is where copying
+// continues; otherwise the destination is truncated and the offset is zero.
+// The resume point comes from the destination's resume marker, never from
+// its size, which a parallel copy can leave with holes below its end.
+func (t *transferTask) openDestination(item transferItem, src transferFile) (transferFile, int64, *resumeMarker, error) {
+	if !t.cfg.resume {
+		f, err := t.create(item)
+		return f, 0, nil, err
+	}
+	marker := newResumeMarker(t.dst, item.dst, item.size, int64(t.cfg.bufferSize))
+	offset := marker.load()
+	info, err := t.dst.Stat(item.dst)
+	if offset == 0 || err != nil || !info.Mode().IsRegular() || info.Size() < offset {
+		marker.discard()
+		f, err := t.create(item)
+		return f, 0, marker, err
+	}
+	f, err := t.dst.OpenWrite(item.dst)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if t.cfg.verifyResume {
+		same, err := sameRange(src, f, offset-min(resumeTail, offset), offset)
+		if err != nil || !same {
+			f.Close()
+			marker.discard()
+			f, err := t.create(item)
+			return f, 0, marker, err
+		}
+	}
+	marker.start(offset)
+	return f, offset, marker, nil
+}
+
+// create truncates item's destination. Remote files are grown to their final
+// size up front so the server reserves the space before any data is sent.
+func (t *transferTask) create(item transferItem) (transferFile, error) {
+	f, err := t.dst.Create(item.dst)
+	if err != nil {
+		return nil, err
+	}
+	if rf, ok := f.(*sftp.File); ok {
+		_ = preallocateRemote(rf, item.size)
+	}
+	return f, nil
+}
+
+// sameRange reports whether a and b hold the same bytes in [from, to).
+func sameRange(a, b io.ReaderAt, from, to int64) (bool, error) {
+	bufA := make([]byte, to-from)
+	bufB := make([]byte, to-from)
+	if _, err := a.ReadAt(bufA, from); err != nil {
+		return false, err
+	}
+	if _, err := b.ReadAt(bufB, from); err != nil {
+		return false, err
+	}
+	return bytes.Equal(bufA, bufB), nil
+}
+
+func (t *transferTask) setCurrent(job *transferJob, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = job
+	t.currentName = name
+}
+
+func (t *transferTask) progress() taskProgress {
+	t.mu.Lock()
+	job, name := t.current, t.currentName
+	t.mu.Unlock()
+
+	p := taskProgress{
+		files:       int(t.files.Load()),
+		filesDone:   int(t.filesDone.Load()),
+		total:       t.totalBytes.Load(),
+		transferred: t.doneBytes.Load(),
+		skipped:     t.skipped.Load(),
+		saved:       t.saved.Load(),
+		file:        name,
+	}
+	if job != nil {
+		p.fileTotal = job.size
+		p.fileTransferred = job.transferredBytes()
+		p.transferred += p.fileTransferred
+	}
+	return p
+}
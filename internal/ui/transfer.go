@@ -1,8 +1,9 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -15,22 +16,33 @@ import (
 
 type transferTickMsg struct{}
 type transferDoneMsg struct {
+	id  int
 	err error
 }
 
 type transferJob struct {
-	reader      io.Reader
-	writer      io.Writer
+	ctl         *transferControl
 	readerAt    io.ReaderAt
 	writerAt    io.WriterAt
+	offset      int64
 	size        int64
 	bufferSize  int
+	blockSize   int64
+	blocks      []int64
 	streams     int
-	closers     []io.Closer
+	attempts    int
+	marker      *resumeMarker
 	transferred atomic.Int64
+
+	mu      sync.Mutex
+	gen     int
+	closers []io.Closer
+	reopen  func() (io.ReaderAt, io.WriterAt, []io.Closer, error)
 }
 
-func newTransferJob(reader io.Reader, writer io.Writer, size int64, closers []io.Closer, cfg transferConfig, readerAt io.ReaderAt, writerAt io.WriterAt) *transferJob {
+// newTransferJob copies size bytes from readerAt to writerAt, starting at
+// offset when resuming a partial destination.
+func newTransferJob(ctl *transferControl, readerAt io.ReaderAt, writerAt io.WriterAt, offset, size int64, closers []io.Closer, cfg transferConfig) *transferJob {
 	if cfg.bufferSize <= 0 {
 		cfg.bufferSize = 8 * 1024 * 1024
 	}
@@ -38,61 +50,71 @@ func newTransferJob(reader io.Reader, writer io.Writer, size int64, closers []io
 	if streams <= 0 {
 		streams = 1
 	}
-	return &transferJob{
-		reader:     reader,
-		writer:     writer,
+	j := &transferJob{
+		ctl:        ctl,
 		readerAt:   readerAt,
 		writerAt:   writerAt,
+		offset:     offset,
 		size:       size,
 		bufferSize: cfg.bufferSize,
+		blockSize:  int64(cfg.bufferSize),
 		streams:    streams,
+		attempts:   max(cfg.attempts, 1),
 		closers:    closers,
 	}
+	j.transferred.Store(offset)
+	return j
 }
 
 func (j *transferJob) run() error {
-	if j.shouldUseParallel() {
+	if j.blocks != nil || j.shouldUseParallel() {
 		return j.copyParallel()
 	}
 	return j.copySequential()
 }
 
 func (j *transferJob) shouldUseParallel() bool {
-	return j.streams > 1 && j.readerAt != nil && j.writerAt != nil && j.size > int64(j.bufferSize)
+	return j.streams > 1 && j.readerAt != nil && j.writerAt != nil && j.size-j.offset > int64(j.bufferSize)
 }
 
+// copySequential copies the rest of the file as one section, so it gets the
+// same retries as a parallel block.
 func (j *transferJob) copySequential() error {
-	buffer := make([]byte, j.bufferSize)
-	writer := &countingWriter{dst: j.writer, job: j}
-	_, err := io.CopyBuffer(writer, j.reader, buffer)
-	return err
+	return j.copySection(j.offset, j.size-j.offset, make([]byte, j.bufferSize))
 }
 
+// copyParallel hands out blocks in file order to the streams: every block from
+// offset on, or just those listed in blocks for a delta copy. Blocks finish
+// out of order, so a block stuck in retries leaves a hole below blocks that
+// are already written; the resume marker only advances over the gap-free
+// prefix.
 func (j *transferJob) copyParallel() error {
 	streams := j.streams
-	if streams <= 1 {
+	if streams <= 1 && j.blocks == nil {
 		return j.copySequential()
 	}
-	chunkSize := (j.size + int64(streams) - 1) / int64(streams)
+	block := j.blockSize
+	var next atomic.Int64
+	var failed atomic.Bool
 	var wg sync.WaitGroup
 	errCh := make(chan error, streams)
 	for i := 0; i < streams; i++ {
-		offset := int64(i) * chunkSize
-		length := minInt64(chunkSize, j.size-offset)
-		if length <= 0 {
-			continue
-		}
 		wg.Add(1)
-		go func(off, ln int64) {
+		go func() {
 			defer wg.Done()
-			reader := io.NewSectionReader(j.readerAt, off, ln)
-			writer := &writerAtSection{WriterAt: j.writerAt, offset: off}
 			buffer := make([]byte, j.bufferSize)
-			_, err := io.CopyBuffer(&countingWriter{dst: writer, job: j}, reader, buffer)
-			if err != nil && err != io.EOF {
-				errCh <- err
+			for !failed.Load() {
+				off, ok := j.blockOffset(next.Add(1) - 1)
+				if !ok {
+					return
+				}
+				if err := j.copySection(off, minInt64(block, j.size-off), buffer); err != nil {
+					failed.Store(true)
+					errCh <- err
+					return
+				}
 			}
-		}(offset, length)
+		}()
 	}
 	wg.Wait()
 	close(errCh)
@@ -104,6 +126,18 @@ func (j *transferJob) copyParallel() error {
 	return nil
 }
 
+// blockOffset returns where the i-th block to copy starts.
+func (j *transferJob) blockOffset(i int64) (int64, bool) {
+	if j.blocks != nil {
+		if i >= int64(len(j.blocks)) {
+			return 0, false
+		}
+		return j.blocks[i], true
+	}
+	off := j.offset + i*j.blockSize
+	return off, off < j.size
+}
+
 func (j *transferJob) add(n int64) {
 	if n > 0 {
 		j.transferred.Add(n)
@@ -115,6 +149,8 @@ func (j *transferJob) transferredBytes() int64 {
 }
 
 func (j *transferJob) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 	var err error
 	for _, c := range j.closers {
 		if c == nil {
@@ -134,179 +170,134 @@ type countingWriter struct {
 }
 
 func (w *countingWriter) Write(p []byte) (int, error) {
-	n, err := w.dst.Write(p)
-	if n > 0 {
-		w.job.add(int64(n))
+	written := 0
+	for len(p) > 0 {
+		ctl := w.job.ctl
+		if err := ctl.wait(); err != nil {
+			return written, err
+		}
+		chunk := p
+		if len(chunk) > limitChunk && ctl.limited() {
+			chunk = chunk[:limitChunk]
+		}
+		if err := ctl.throttle(len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.dst.Write(chunk)
+		if n > 0 {
+			w.job.add(int64(n))
+			written += n
+		}
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+		p = p[n:]
 	}
-	return n, err
+	return written, nil
 }
 
+// writerAtSection writes sequentially from offset and reports the written
+// part of the section that began at start to the resume marker.
 type writerAtSection struct {
 	io.WriterAt
 	offset int64
+	start  int64
+	marker *resumeMarker
 }
 
 func (w *writerAtSection) Write(p []byte) (int, error) {
 	n, err := w.WriterAt.WriteAt(p, w.offset)
 	w.offset += int64(n)
+	if n > 0 {
+		w.marker.advance(w.start, w.offset)
+	}
 	return n, err
 }
 
 func (m *model) uploadSelected() tea.Cmd {
-	if len(m.panes) < 2 || m.client == nil {
+	if len(m.panes) < 2 || m.remote() == nil {
 		return tea.Printf("remote client unavailable")
 	}
-	if m.transfer.active {
-		return tea.Printf("transfer already running")
-	}
 	src := m.panes[paneLocal]
 	dst := m.panes[paneRemote]
 	row := src.table.SelectedRow()
-	if row == nil {
+	if row == nil || row[colName] == ".." {
 		return tea.Printf("no file selected")
 	}
-	if row[colType] == rowTypeDir {
-		return tea.Printf("directories not supported yet")
-	}
-	localPath := filepath.Join(src.cwd, row[colName])
-	remotePath := filepath.Join(dst.cwd, row[colName])
-	file, err := os.Open(localPath)
-	if err != nil {
-		return tea.Printf("open local file: %v", err)
-	}
-	adviseSequential(file)
-	info, err := file.Stat()
-	if err != nil {
-		file.Close()
-		return tea.Printf("stat local file: %v", err)
-	}
-	if err := ensureRemoteDir(m.client, filepath.Dir(remotePath)); err != nil {
-		file.Close()
-		return tea.Printf("prep remote dir: %v", err)
-	}
-	rc, err := m.client.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
-	if err != nil {
-		file.Close()
-		return tea.Printf("create remote file: %v", err)
-	}
-	_ = preallocateRemote(rc, info.Size())
-	m.setupTransferJob(
-		newTransferJob(file, rc, info.Size(), []io.Closer{file, rc}, m.transferCfg, file, rc),
-		transferState{
-			active:      true,
-			direction:   "Upload",
-			filename:    filepath.Base(localPath),
-			total:       info.Size(),
-			started:     time.Now(),
-			lastUpdate:  time.Now(),
-			refreshPane: paneRemote,
-		},
-	)
-	return m.startTransfer()
+	return m.enqueue(newTransferTask(
+		"Upload",
+		localFS{},
+		remoteFS{client: m.client},
+		filepath.Join(src.cwd, row[colName]),
+		filepath.Join(dst.cwd, row[colName]),
+		paneRemote,
+		m.transferCfg,
+	))
 }
 
 func (m *model) downloadSelected() tea.Cmd {
-	if len(m.panes) < 2 || m.client == nil {
+	if len(m.panes) < 2 || m.remote() == nil {
 		return tea.Printf("remote client unavailable")
 	}
-	if m.transfer.active {
-		return tea.Printf("transfer already running")
-	}
 	remotePane := m.panes[paneRemote]
 	localPane := m.panes[paneLocal]
 	row := remotePane.table.SelectedRow()
-	if row == nil {
+	if row == nil || row[colName] == ".." {
 		return tea.Printf("no file selected")
 	}
-	if row[colType] == rowTypeDir {
-		return tea.Printf("directories not supported yet")
-	}
-	remotePath := filepath.Join(remotePane.cwd, row[colName])
-	localPath := filepath.Join(localPane.cwd, row[colName])
-	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-		return tea.Printf("prepare local dir: %v", err)
-	}
-	remoteFile, err := m.client.Open(remotePath)
-	if err != nil {
-		return tea.Printf("open remote file: %v", err)
-	}
-	info, err := remoteFile.Stat()
-	if err != nil {
-		remoteFile.Close()
-		return tea.Printf("stat remote file: %v", err)
-	}
-	localFile, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-	if err != nil {
-		remoteFile.Close()
-		return tea.Printf("create local file: %v", err)
-	}
-	adviseSequential(localFile)
-	m.setupTransferJob(
-		newTransferJob(remoteFile, localFile, info.Size(), []io.Closer{remoteFile, localFile}, m.transferCfg, remoteFile, localFile),
-		transferState{
-			active:      true,
-			direction:   "Download",
-			filename:    filepath.Base(remotePath),
-			total:       info.Size(),
-			started:     time.Now(),
-			lastUpdate:  time.Now(),
-			refreshPane: paneLocal,
-		},
-	)
-	return m.startTransfer()
-}
-
-func (m *model) setupTransferJob(job *transferJob, state transferState) {
-	m.job = job
-	state.transferred = 0
-	m.transfer = state
-}
-
-func (m *model) startTransfer() tea.Cmd {
-	return tea.Batch(m.runTransferJob(), m.scheduleProgressTick())
-}
-
-func (m *model) runTransferJob() tea.Cmd {
-	job := m.job
-	if job == nil {
-		return nil
-	}
-	return func() tea.Msg {
-		err := job.run()
-		return transferDoneMsg{err: err}
-	}
+	return m.enqueue(newTransferTask(
+		"Download",
+		remoteFS{client: m.client},
+		localFS{},
+		filepath.Join(remotePane.cwd, row[colName]),
+		filepath.Join(localPane.cwd, row[colName]),
+		paneLocal,
+		m.transferCfg,
+	))
 }
 
 func (m *model) scheduleProgressTick() tea.Cmd {
-	if !m.transfer.active {
-		return nil
-	}
 	interval := m.transferCfg.progressInterval
 	return tea.Tick(interval, func(time.Time) tea.Msg {
 		return transferTickMsg{}
 	})
 }
 
+// handleTransferTick samples every running entry and keeps ticking while any
+// transfer is in flight.
 func (m *model) handleTransferTick() tea.Cmd {
-	if !m.transfer.active || m.job == nil {
+	running := m.queue.withStatus(entryRunning)
+	if len(running) == 0 {
+		m.ticking = false
 		return nil
 	}
-	total := m.job.transferredBytes()
-	delta := total - m.transfer.transferred
-	if delta > 0 {
-		now := time.Now()
-		elapsed := now.Sub(m.transfer.lastUpdate).Seconds()
-		if elapsed <= 0 {
-			elapsed = 1e-6
-		}
-		instant := float64(delta) / elapsed
-		m.transfer.rate = smoothRate(m.transfer.rate, instant)
-		m.transfer.lastUpdate = now
-		m.transfer.transferred = total
+	now := time.Now()
+	for _, e := range running {
+		e.state.sample(e.task.progress(), now)
+		e.state.paused = e.task.ctl.isPaused()
 	}
 	return m.scheduleProgressTick()
 }
 
+func (t *transferState) sample(progress taskProgress, now time.Time) {
+	delta := (progress.transferred - progress.skipped) - (t.transferred - t.skipped)
+	t.applyProgress(progress)
+	t.transferred = progress.transferred
+	if delta <= 0 {
+		return
+	}
+	elapsed := now.Sub(t.lastUpdate).Seconds()
+	if elapsed <= 0 {
+		elapsed = 1e-6
+	}
+	instant := float64(delta) / elapsed
+	t.rate = smoothRate(t.rate, instant)
+	t.lastUpdate = now
+}
+
 func smoothRate(previous, instant float64) float64 {
 	const alpha = 0.35
 	if previous <= 0 {
@@ -318,27 +309,43 @@ func smoothRate(previous, instant float64) float64 {
 	return previous*(1-alpha) + instant*alpha
 }
 
-func (m *model) finishTransfer(resultErr error) tea.Cmd {
-	if m.job != nil {
-		m.transfer.transferred = m.job.transferredBytes()
-		if cerr := m.job.close(); resultErr == nil {
-			resultErr = cerr
-		}
-		m.job = nil
-	}
-	m.transfer.active = false
-	m.transfer.err = resultErr
-	refreshPane := m.transfer.refreshPane
-	m.transfer.refreshPane = 0
-
-	var cmds []tea.Cmd
-	if resultErr == nil {
-		if refreshPane >= 0 && refreshPane < len(m.panes) {
-			_ = m.panes[refreshPane].changeDirectory(m.panes[refreshPane].cwd)
+func (m *model) finishTransfer(id int, resultErr error) tea.Cmd {
+	e := m.queue.find(id)
+	if e == nil {
+		return m.schedule()
+	}
+	progress := e.task.progress()
+	e.state.applyProgress(progress)
+	e.state.transferred = progress.transferred
+	e.state.active = false
+	e.state.paused = false
+	e.state.err = resultErr
+	switch {
+	case resultErr == nil:
+		e.status = entryDone
+	case errors.Is(resultErr, context.Canceled):
+		e.status = entryCanceled
+		e.state.err = nil
+	default:
+		e.status = entryFailed
+	}
+
+	if m.quitting && len(m.queue.withStatus(entryRunning)) == 0 {
+		return tea.Quit
+	}
+	cmds := []tea.Cmd{m.schedule()}
+	switch e.status {
+	case entryCanceled:
+		cmds = append(cmds, tea.Printf("%s canceled: %s", strings.ToLower(e.state.direction), e.state.filename))
+	case entryDone:
+		for i, p := range m.panes {
+			if e.state.refreshPane == i || e.state.refreshPane == paneAll {
+				_ = p.changeDirectory(p.cwd)
+			}
 		}
-		cmds = append(cmds, tea.Printf("%s complete: %s", strings.ToLower(m.transfer.direction), m.transfer.filename))
-	} else {
-		cmds = append(cmds, tea.Printf("%s failed: %v", strings.ToLower(m.transfer.direction), resultErr))
+		cmds = append(cmds, tea.Printf("%s complete: %s", strings.ToLower(e.state.direction), e.state.filename))
+	default:
+		cmds = append(cmds, tea.Printf("%s failed: %v", strings.ToLower(e.state.direction), resultErr))
 	}
 	return tea.Batch(cmds...)
 }
@@ -357,6 +364,17 @@ func preallocateRemote(f *sftp.File, size int64) error {
 	return f.Truncate(size)
 }
 
+func (t *transferState) applyProgress(p taskProgress) {
+	t.total = p.total
+	t.files = p.files
+	t.filesDone = p.filesDone
+	t.file = p.file
+	t.fileTotal = p.fileTotal
+	t.fileTransferred = p.fileTransferred
+	t.skipped = p.skipped
+	t.saved = p.saved
+}
+
 func (t transferState) percent() float64 {
 	if t.total == 0 {
 		return 0
@@ -376,7 +394,7 @@ func (t transferState) speed() float64 {
 	if elapsed <= 0 {
 		return 0
 	}
-	return float64(t.transferred) / elapsed
+	return float64(t.transferred-t.skipped) / elapsed
 }
 
 func (t transferState) currentSpeed() float64 {
@@ -407,3 +425,10 @@ func minInt64(a, b int64) int64 {
 	}
 	return b
 }
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -6,7 +6,8 @@ import (
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/pkg/sftp"
+
+	"github.com/m1kkY8/termftp/internal/sftpclient"
 )
 
 const (
@@ -23,6 +24,8 @@ const (
 const (
 	paneLocal = iota
 	paneRemote
+
+	paneAll = -1
 )
 
 var (
@@ -45,15 +48,21 @@ type entry struct {
 }
 
 type model struct {
-	panes       []*pane
-	focused     int
-	width       int
-	height      int
-	client      *sftp.Client
-	progress    progress.Model
-	transfer    transferState
-	job         *transferJob
-	transferCfg transferConfig
+	panes          []*pane
+	focused        int
+	width          int
+	height         int
+	client         *sftpclient.Client
+	dialog         *promptDialog
+	pendingPrompts []promptRequest
+	progress       progress.Model
+	queue          transferQueue
+	showQueue      bool
+	syncPlan       *syncPlan
+	watch          *localWatch
+	ticking        bool
+	quitting       bool
+	transferCfg    transferConfig
 }
 
 type pane struct {
@@ -66,6 +75,7 @@ type pane struct {
 	height   int
 	focused  bool
 	readonly bool
+	status   string
 }
 
 type transferState struct {
@@ -79,23 +89,68 @@ type transferState struct {
 	lastUpdate  time.Time
 	rate        float64
 	refreshPane int
+	paused      bool
+	skipped     int64
+	saved       int64
+
+	files           int
+	filesDone       int
+	file            string
+	fileTotal       int64
+	fileTransferred int64
 }
 
 type transferConfig struct {
 	bufferSize       int
 	streams          int
 	progressInterval time.Duration
+	concurrent       int
+	keepPartial      bool
+	resume           bool
+	verifyResume     bool
+	verify           bool
+	limiter          *rateLimiter
+	transferLimit    int64
+	attempts         int
+	conflict         string
+	prompter         *Prompter
+	preserveTimes    bool
+	preserveMode     bool
+	delta            bool
+	syncChecksum     bool
+	syncDelete       bool
+	syncStateDir     string
+	watchDebounce    time.Duration
+	watchDelete      bool
 }
 
 type Options struct {
 	LocalRoot  string
 	RemoteRoot string
-	Client     *sftp.Client
+	Client     *sftpclient.Client
+	Prompter   *Prompter
 	Transfer   TransferOptions
 }
 
 type TransferOptions struct {
-	BufferSize       int
-	ParallelStreams  int
-	ProgressInterval time.Duration
+	BufferSize          int
+	ParallelStreams     int
+	ProgressInterval    time.Duration
+	ConcurrentTransfers int
+	KeepPartial         bool
+	Resume              bool
+	VerifyResume        bool
+	VerifyChecksums     bool
+	MaxBandwidth        int64
+	TransferBandwidth   int64
+	MaxAttempts         int
+	Conflict            string
+	PreserveTimes       bool
+	PreserveMode        bool
+	Delta               bool
+	SyncChecksums       bool
+	SyncDeletes         bool
+	SyncStateDir        string
+	WatchDebounce       time.Duration
+	WatchDeletes        bool
 }
@@ -10,6 +10,12 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.resize(msg.Width, msg.Height)
 		return m, nil
 	case tea.KeyMsg:
+		if m.syncPlan != nil && m.dialog == nil {
+			return m, m.handleSyncKey(msg)
+		}
+		if m.showQueue && m.dialog == nil {
+			return m, m.handleQueueKey(msg)
+		}
 		if cmd := m.handleKey(msg); cmd != nil {
 			return m, cmd
 		}
@@ -18,7 +24,22 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 	case transferDoneMsg:
-		return m, m.finishTransfer(msg.err)
+		return m, m.finishTransfer(msg.id, msg.err)
+	case syncPlanMsg:
+		return m, m.handleSyncPlan(msg)
+	case watchBatchMsg:
+		return m, m.handleWatchBatch(msg)
+	case watchPlanMsg:
+		return m, m.handleWatchPlan(msg)
+	case connStatusMsg:
+		return m, m.handleConnStatus(msg.status)
+	case promptMsg:
+		if m.dialog != nil {
+			m.pendingPrompts = append(m.pendingPrompts, msg.req)
+			return m, nil
+		}
+		m.dialog = newPromptDialog(msg.req)
+		return m, nil
 	}
 
 	cmds := make([]tea.Cmd, 0, len(m.panes))
@@ -33,9 +54,23 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	if m.dialog != nil {
+		if msg.String() == "ctrl+c" {
+			m.dialog.cancel(errCanceled)
+			for _, req := range m.pendingPrompts {
+				newPromptDialog(req).cancel(errCanceled)
+			}
+			m.dialog, m.pendingPrompts = nil, nil
+			return m.quit()
+		}
+		if m.dialog.handleKey(msg) {
+			m.nextDialog()
+		}
+		return nil
+	}
 	switch msg.String() {
 	case "q", "ctrl+c":
-		return tea.Quit
+		return m.quit()
 	case "tab":
 		m.toggleFocus()
 	case "ctrl+l":
@@ -70,6 +105,34 @@ func (m *model) handleKey(msg tea.KeyMsg) tea.Cmd {
 		return m.uploadSelected()
 	case "g":
 		return m.downloadSelected()
+	case "s":
+		m.togglePauseAll()
+	case "X":
+		m.cancelRunning()
+	case "+", "=":
+		m.transferCfg.limiter.step(1)
+	case "-":
+		m.transferCfg.limiter.step(-1)
+	case "m":
+		return m.startSync(true)
+	case "M":
+		return m.startSync(false)
+	case "b":
+		return m.startTwoWaySync()
+	case "w":
+		return m.toggleWatch()
+	case "t":
+		m.showQueue = true
+		m.queue.moveCursor(0)
 	}
 	return nil
 }
+
+// nextDialog shows the oldest prompt that arrived while another was open.
+func (m *model) nextDialog() {
+	m.dialog = nil
+	if len(m.pendingPrompts) > 0 {
+		m.dialog = newPromptDialog(m.pendingPrompts[0])
+		m.pendingPrompts = m.pendingPrompts[1:]
+	}
+}
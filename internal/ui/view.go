@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -30,6 +31,15 @@ func (m *model) View() string {
 	if width == 0 {
 		width = m.width
 	}
+	if m.showQueue {
+		panes = m.renderQueue(width)
+	}
+	if m.syncPlan != nil {
+		panes = m.renderSyncPlan(width)
+	}
+	if m.dialog != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, panes, "", m.dialog.view(width))
+	}
 	transfer := m.renderTransferPane(width)
 	return lipgloss.JoinVertical(lipgloss.Left, panes, "", transfer)
 }
@@ -43,12 +53,15 @@ func (p *pane) render() string {
 		Width(p.width).
 		BorderForeground(border)
 
-	title := fmt.Sprintf("%s: %s", p.title, p.cwd)
+	title := headerStyle.Render(fmt.Sprintf("%s: %s", p.title, p.cwd))
+	if p.status != "" {
+		title += "  " + p.status
+	}
 	body := panelStyle.Render(p.table.View())
 	if p.err != nil {
 		body += "\n" + errorStyle.Render(p.err.Error())
 	}
-	return headerStyle.Render(title) + "\n" + body
+	return title + "\n" + body
 }
 
 func (m *model) renderTransferPane(width int) string {
@@ -61,30 +74,115 @@ func (m *model) renderTransferPane(width int) string {
 		}
 	}
 	width = max(20, width)
+	m.progress.Width = max(10, width-4)
+	var sections []string
+	for _, e := range m.queue.withStatus(entryRunning) {
+		sections = append(sections, m.renderTransfer(e.state))
+	}
+	hints := m.queueSummary()
+	if limit := m.transferCfg.limiter.limit(); limit > 0 {
+		hints = strings.TrimPrefix(hints+" • limit "+formatRate(limit), " • ")
+	}
+	if len(sections) > 0 {
+		hints = strings.TrimPrefix(hints+" • s: pause/resume • X: cancel • +/-: limit", " • ")
+	}
+	if m.watch != nil {
+		hints = strings.TrimPrefix(hints+fmt.Sprintf(" • watching %s → %s • w: stop", m.watch.localRoot, m.watch.remoteRoot), " • ")
+	}
+	if hints != "" {
+		sections = append(sections, hintStyle.Render(hints+" • t: queue"))
+	}
 	body := "No active transfers"
-	if m.transfer.active {
-		percent := math.Max(0, math.Min(1, m.transfer.percent()))
-		m.progress.Width = max(10, width-4)
-		bar := m.progress.ViewAs(percent)
-		stats := fmt.Sprintf(
-			"%s %s %s/%s (%s) • %s • ETA %s • Elapsed %s",
-			m.transfer.direction,
-			formatFilename(m.transfer.filename),
-			formatBytes(m.transfer.transferred),
-			formatBytes(m.transfer.total),
-			formatPercent(percent, m.transfer.total > 0),
-			formatSpeed(m.transfer),
-			formatETA(m.transfer),
-			formatElapsed(m.transfer),
-		)
-		body = stats + "\n" + bar
-	} else if m.transfer.err != nil {
-		body = errorStyle.Render(m.transfer.err.Error())
+	if len(sections) > 0 {
+		body = strings.Join(sections, "\n")
 	}
 	panel := transferPaneStyle.MaxWidth(width).Render(body)
 	return lipgloss.JoinVertical(lipgloss.Left, headerStyle.Render("Transfer"), panel)
 }
 
+func (m *model) renderTransfer(t transferState) string {
+	percent := math.Max(0, math.Min(1, t.percent()))
+	stats := fmt.Sprintf(
+		"%s %s%s %s/%s (%s) • %s • ETA %s • Elapsed %s",
+		t.direction,
+		formatFilename(t.filename),
+		formatFileCount(t),
+		formatBytes(t.transferred),
+		formatBytes(t.total),
+		formatPercent(percent, t.total > 0),
+		formatSpeed(t),
+		formatETA(t),
+		formatElapsed(t),
+	)
+	if t.saved > 0 {
+		stats += " • saved " + formatBytes(t.saved)
+	}
+	if t.paused {
+		stats += " • Paused"
+	}
+	body := stats + "\n" + m.progress.ViewAs(percent)
+	if t.files > 1 && t.file != "" {
+		body += "\n" + m.renderCurrentFile(t)
+	}
+	return body
+}
+
+// renderCurrentFile shows per-file progress below the overall bar for
+// multi-file transfers.
+func (m *model) renderCurrentFile(t transferState) string {
+	filePercent := 0.0
+	if t.fileTotal > 0 {
+		filePercent = math.Max(0, math.Min(1, float64(t.fileTransferred)/float64(t.fileTotal)))
+	}
+	line := fmt.Sprintf(
+		"↳ %s %s/%s (%s)",
+		t.file,
+		formatBytes(t.fileTransferred),
+		formatBytes(t.fileTotal),
+		formatPercent(filePercent, t.fileTotal > 0),
+	)
+	return line + "\n" + m.progress.ViewAs(filePercent)
+}
+
+// renderQueue lists every queued transfer in run order, in place of the panes.
+func (m *model) renderQueue(width int) string {
+	if width <= 0 {
+		width = max(20, m.width)
+	}
+	var lines []string
+	if len(m.queue.entries) == 0 {
+		lines = append(lines, "Queue is empty")
+	}
+	for i, e := range m.queue.entries {
+		cursor := "  "
+		if i == m.queue.cursor {
+			cursor = "> "
+		}
+		line := fmt.Sprintf(
+			"%s%-8s %s %s %s/%s",
+			cursor,
+			e.statusName(),
+			e.state.direction,
+			formatFilename(e.state.filename),
+			formatBytes(e.state.transferred),
+			formatBytes(e.state.total),
+		)
+		if limit := e.task.ctl.limiter.limit(); limit > 0 && !e.finished() {
+			line += " ≤ " + formatRate(limit)
+		}
+		if e.state.err != nil {
+			line += " " + errorStyle.Render(e.state.err.Error())
+		}
+		if i == m.queue.cursor {
+			line = headerStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", hintStyle.Render("j/k select • J/K reorder • space pause • +/- limit • x cancel/remove • r retry • c clear finished • t/esc close"))
+	panel := transferPaneStyle.Width(max(20, width-2)).Render(strings.Join(lines, "\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, headerStyle.Render("Queue"), panel)
+}
+
 func formatSpeed(t transferState) string {
 	speed := t.currentSpeed()
 	if speed <= 0 {
@@ -93,6 +191,10 @@ func formatSpeed(t transferState) string {
 	return fmt.Sprintf("%s/s", humanSize(int64(speed)))
 }
 
+func formatRate(rate int64) string {
+	return fmt.Sprintf("%s/s", humanSize(rate))
+}
+
 func formatETA(t transferState) string {
 	eta := t.eta()
 	if eta <= 0 || eta > 24*time.Hour {
@@ -137,6 +239,17 @@ func formatPercent(percent float64, known bool) string {
 	return fmt.Sprintf("%3.0f%%", percent*100)
 }
 
+func formatFileCount(t transferState) string {
+	if t.files <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" %d/%d files •", t.filesDone, t.files)
+}
+
+func formatCount(n int, label string) string {
+	return fmt.Sprintf("%d %s", n, label)
+}
+
 func formatFilename(name string) string {
 	if name == "" {
 		return ""
@@ -0,0 +1,280 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// localWatch uploads changes below localRoot to remoteRoot as they happen,
+// in batches once the tree has been quiet for the debounce delay.
+type localWatch struct {
+	localRoot  string
+	remoteRoot string
+	closer     io.Closer
+	batches    chan []string
+	done       chan struct{}
+}
+
+type watchBatchMsg struct {
+	watch *localWatch
+	paths []string
+}
+
+type watchPlanMsg struct {
+	plan *syncPlan
+	err  error
+}
+
+// watchIgnored reports whether a changed file is scratch that should not be
+// uploaded: editor swap and backup files, the file vim creates to probe
+// whether it may write to a directory, and termftp's own temporary files.
+func watchIgnored(name string) bool {
+	switch {
+	case name == "4913",
+		strings.HasSuffix(name, "~"),
+		strings.HasPrefix(name, ".") && (strings.HasSuffix(name, ".swp") || strings.HasSuffix(name, ".swo") || strings.HasSuffix(name, ".swx")),
+		strings.HasSuffix(name, partSuffix),
+		strings.HasSuffix(name, resumeSuffix),
+		strings.HasSuffix(name, asideSuffix):
+		return true
+	}
+	return false
+}
+
+func startLocalWatch(localRoot, remoteRoot string, delay time.Duration) (*localWatch, error) {
+	changed := make(chan string, 64)
+	w := &localWatch{
+		localRoot:  localRoot,
+		remoteRoot: remoteRoot,
+		batches:    make(chan []string),
+		done:       make(chan struct{}),
+	}
+	closer, err := startWatcher(localRoot, changed, w.done)
+	if err != nil {
+		return nil, err
+	}
+	w.closer = closer
+	go w.debounce(changed, delay)
+	return w, nil
+}
+
+func (w *localWatch) stop() {
+	close(w.done)
+	_ = w.closer.Close()
+}
+
+// debounce collects changed paths and hands them over as one batch once no
+// new change has come in for delay.
+func (w *localWatch) debounce(changed <-chan string, delay time.Duration) {
+	pending := map[string]bool{}
+	timer := time.NewTimer(delay)
+	timer.Stop()
+	for {
+		select {
+		case path := <-changed:
+			pending[path] = true
+			timer.Reset(delay)
+		case <-timer.C:
+			batch := make([]string, 0, len(pending))
+			for path := range pending {
+				batch = append(batch, path)
+			}
+			pending = map[string]bool{}
+			select {
+			case w.batches <- batch:
+			case <-w.done:
+				return
+			}
+		case <-w.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// next delivers the next batch of changed paths as a message.
+func (w *localWatch) next() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case paths := <-w.batches:
+			return watchBatchMsg{watch: w, paths: paths}
+		case <-w.done:
+			return nil
+		}
+	}
+}
+
+// toggleWatch starts watching the local working directory, uploading into
+// the remote one, or stops the running watch.
+func (m *model) toggleWatch() tea.Cmd {
+	if m.watch != nil {
+		root := m.watch.localRoot
+		m.watch.stop()
+		m.watch = nil
+		return tea.Printf("stopped watching %s", root)
+	}
+	if len(m.panes) < 2 || m.remote() == nil {
+		return tea.Printf("remote client unavailable")
+	}
+	localRoot, remoteRoot := m.panes[paneLocal].cwd, m.panes[paneRemote].cwd
+	w, err := startLocalWatch(localRoot, remoteRoot, m.transferCfg.watchDebounce)
+	if err != nil {
+		return tea.Printf("watch failed: %v", err)
+	}
+	m.watch = w
+	return tea.Batch(tea.Printf("watching %s → %s", localRoot, remoteRoot), w.next())
+}
+
+func (m *model) handleWatchBatch(msg watchBatchMsg) tea.Cmd {
+	if msg.watch != m.watch {
+		return nil
+	}
+	w, cfg := m.watch, m.transferCfg
+	local, remote := localFS{}, remoteFS{client: m.client}
+	plan := func() tea.Msg {
+		p, err := planWatch(local, remote, w.localRoot, w.remoteRoot, msg.paths, cfg)
+		return watchPlanMsg{plan: p, err: err}
+	}
+	return tea.Batch(plan, w.next())
+}
+
+func (m *model) handleWatchPlan(msg watchPlanMsg) tea.Cmd {
+	if msg.err != nil {
+		return tea.Printf("watch upload failed: %v", msg.err)
+	}
+	p := msg.plan
+	if len(p.actions) == 0 {
+		return nil
+	}
+	task := p.task(m.transferCfg)
+	task.direction = "Upload"
+	task.name = filepath.Base(p.actions[0].rel)
+	if len(p.actions) > 1 {
+		task.name = fmt.Sprintf("%d changes", len(p.actions))
+	}
+	return m.enqueue(task)
+}
+
+// planWatch turns a batch of changed local paths into a one-way plan against
+// the remote directory. A file is sent unless the remote copy already has its
+// size and modification time, as after a download, and a directory is
+// compared like a sync. Deleted paths are removed remotely only when the
+// config asks for it.
+func planWatch(local, remote fileSystem, localRoot, remoteRoot string, paths []string, cfg transferConfig) (*syncPlan, error) {
+	leg := newSyncLeg(local, remote, remoteRoot)
+	p := &syncPlan{
+		direction:   "local → remote",
+		srcRoot:     localRoot,
+		dstRoot:     remoteRoot,
+		refreshPane: paneRemote,
+		legs:        []*syncLeg{leg},
+	}
+	cfg.syncChecksum = false
+	cfg.syncDelete = cfg.watchDelete
+	changed := map[string]bool{}
+	for _, path := range paths {
+		changed[path] = true
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if underChanged(changed, localRoot, path) {
+			continue
+		}
+		rel, err := filepath.Rel(localRoot, path)
+		if err != nil {
+			return nil, err
+		}
+		target := filepath.Join(remoteRoot, rel)
+		info, err := local.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			sub, err := planSync(p.direction, local, remote, path, target, paneRemote, cfg)
+			if err != nil {
+				return nil, err
+			}
+			p.merge(rel, sub)
+		case err == nil && info.Mode().IsRegular():
+			if existing, err := remote.Stat(target); err == nil && agree(info, existing) {
+				continue
+			}
+			p.actions = append(p.actions, syncAction{op: syncCreate, rel: rel, size: info.Size()})
+			leg.copy(rel, treeEntry{path: path, info: info})
+		case err == nil:
+			// Neither a file nor a directory, so there is nothing to send.
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, err
+		case cfg.watchDelete:
+			if err := p.removeRemote(rel, target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// underChanged reports whether a directory above path, up to root, is in
+// the batch too and so covers it.
+func underChanged(changed map[string]bool, root, path string) bool {
+	for path != root {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return false
+		}
+		if changed[parent] {
+			return true
+		}
+		path = parent
+	}
+	return false
+}
+
+// merge adds a plan for the directory at rel to p's only leg.
+func (p *syncPlan) merge(rel string, sub *syncPlan) {
+	leg, subLeg := p.legs[0], sub.legs[0]
+	leg.items = append(leg.items, subLeg.items...)
+	for _, dir := range subLeg.dirs {
+		if dir.src != "" || !leg.hasDir[dir.dst] {
+			leg.hasDir[dir.dst] = true
+			leg.dirs = append(leg.dirs, dir)
+		}
+	}
+	leg.deletes = append(leg.deletes, subLeg.deletes...)
+	for _, a := range sub.actions {
+		a.rel = filepath.Join(rel, a.rel)
+		p.actions = append(p.actions, a)
+	}
+}
+
+// removeRemote plans the delete of target, and everything below it if it is
+// a directory.
+func (p *syncPlan) removeRemote(rel, target string) error {
+	leg := p.legs[0]
+	info, err := leg.dst.Stat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.actions = append(p.actions, syncAction{op: syncDelete, rel: rel, dir: info.IsDir()})
+	leg.remove(rel)
+	if !info.IsDir() {
+		return nil
+	}
+	_, order, err := scanTree(leg.dst, target)
+	if err != nil {
+		return err
+	}
+	for _, child := range order {
+		leg.remove(filepath.Join(rel, child))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Files are reported once written and closed, or moved into place, which
+// is how editors save; creating an empty file alone is not.
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_DELETE
+
+// inotifyWatcher reports changed paths below root, adding watches for new
+// directories as they appear.
+type inotifyWatcher struct {
+	root    string
+	fd      int
+	file    *os.File
+	dirs    map[int32]string
+	changed chan<- string
+	done    <-chan struct{}
+}
+
+// startWatcher sends every path below root that changes to changed until
+// done is closed or the returned closer is closed.
+func startWatcher(root string, changed chan<- string, done <-chan struct{}) (io.Closer, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		root:    root,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		dirs:    map[int32]string{},
+		changed: changed,
+		done:    done,
+	}
+	if err := w.addTree(root); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.read()
+	return w.file, nil
+}
+
+// addTree watches dir and every directory below it.
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories may vanish while they are being walked.
+			if path != dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		wd, err := unix.InotifyAddWatch(w.fd, path, watchMask)
+		if err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+// read decodes events until the inotify file is closed.
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + unix.SizeofInotifyEvent
+			off = start + int(ev.Len)
+			name := strings.TrimRight(string(buf[start:off]), "\x00")
+			if !w.handle(ev.Wd, ev.Mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle passes on the path an event is about. It returns false once the
+// watch is stopped.
+func (w *inotifyWatcher) handle(wd int32, mask uint32, name string) bool {
+	switch {
+	case mask&unix.IN_Q_OVERFLOW != 0:
+		// Events were lost; have the whole tree compared.
+		return w.notify(w.root)
+	case mask&unix.IN_IGNORED != 0:
+		delete(w.dirs, wd)
+		return true
+	}
+	dir, ok := w.dirs[wd]
+	if !ok || name == "" {
+		return true
+	}
+	path := filepath.Join(dir, name)
+	isDir := mask&unix.IN_ISDIR != 0
+	if isDir && mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+		_ = w.addTree(path)
+	}
+	if !isDir && (mask&unix.IN_CREATE != 0 || watchIgnored(name)) {
+		return true
+	}
+	return w.notify(path)
+}
+
+func (w *inotifyWatcher) notify(path string) bool {
+	select {
+	case w.changed <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
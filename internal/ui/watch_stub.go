@@ -0,0 +1,12 @@
+//go:build !linux
+
+package ui
+
+import (
+	"errors"
+	"io"
+)
+
+func startWatcher(root string, changed chan<- string, done <-chan struct{}) (io.Closer, error) {
+	return nil, errors.New("watch mode needs inotify, which is only available on Linux")
+}